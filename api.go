@@ -0,0 +1,181 @@
+package sat
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// API contains every operation of the SAT API, *Client satisfies this interface.
+// Depend on this interface to mock the client or to decorate it using Middleware
+type API interface {
+	Ping(ctx context.Context, opts ...CallOptionFunc) (*PingResponse, error)
+	Account(ctx context.Context, opts ...CallOptionFunc) (*Account, error)
+	Inquiry(ctx context.Context, req *InquiryRequest, opts ...CallOptionFunc) (*InquiryResponse, error)
+	Checkout(ctx context.Context, req *OrderRequest, opts ...CallOptionFunc) (*OrderDetail, error)
+	CheckStatus(ctx context.Context, requestID string, opts ...CallOptionFunc) (*OrderDetail, error)
+	ListProduct(ctx context.Context, code string, opts ...CallOptionFunc) ([]*Product, error)
+	HandleCallback(impl Callback, opts ...CallbackOptionFunc) http.HandlerFunc
+}
+
+var _ API = (*Client)(nil)
+
+// Operation is the name of SAT API operation
+type Operation string
+
+const (
+	// OperationPing is the operation name of Ping
+	OperationPing Operation = "Ping"
+	// OperationAccount is the operation name of Account
+	OperationAccount Operation = "Account"
+	// OperationInquiry is the operation name of Inquiry
+	OperationInquiry Operation = "Inquiry"
+	// OperationCheckout is the operation name of Checkout
+	OperationCheckout Operation = "Checkout"
+	// OperationCheckStatus is the operation name of CheckStatus
+	OperationCheckStatus Operation = "CheckStatus"
+	// OperationListProduct is the operation name of ListProduct
+	OperationListProduct Operation = "ListProduct"
+)
+
+// Middleware decorates the API, example: caching, metrics or rate limiting
+type Middleware func(next API) API
+
+// Chain wraps the API using the middlewares, the first middleware is the outermost
+func Chain(api API, middlewares ...Middleware) API {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		api = middlewares[i](api)
+	}
+
+	return api
+}
+
+// Decorator is a helper to build a Middleware which overrides only some operations,
+// every operation without override func is passed to Next. The call options are carried to the override func by ctx,
+// so the calls to Next using ctx keep them
+type Decorator struct {
+	Next API
+
+	PingFunc           func(ctx context.Context) (*PingResponse, error)
+	AccountFunc        func(ctx context.Context) (*Account, error)
+	InquiryFunc        func(ctx context.Context, req *InquiryRequest) (*InquiryResponse, error)
+	CheckoutFunc       func(ctx context.Context, req *OrderRequest) (*OrderDetail, error)
+	CheckStatusFunc    func(ctx context.Context, requestID string) (*OrderDetail, error)
+	ListProductFunc    func(ctx context.Context, code string) ([]*Product, error)
+	HandleCallbackFunc func(impl Callback, opts ...CallbackOptionFunc) http.HandlerFunc
+}
+
+// Ping calls PingFunc or Next.Ping
+func (d *Decorator) Ping(ctx context.Context, opts ...CallOptionFunc) (*PingResponse, error) {
+	if d.PingFunc != nil {
+		return d.PingFunc(ContextWithCallOptions(ctx, opts...))
+	}
+
+	return d.Next.Ping(ctx, opts...)
+}
+
+// Account calls AccountFunc or Next.Account
+func (d *Decorator) Account(ctx context.Context, opts ...CallOptionFunc) (*Account, error) {
+	if d.AccountFunc != nil {
+		return d.AccountFunc(ContextWithCallOptions(ctx, opts...))
+	}
+
+	return d.Next.Account(ctx, opts...)
+}
+
+// Inquiry calls InquiryFunc or Next.Inquiry
+func (d *Decorator) Inquiry(ctx context.Context, req *InquiryRequest, opts ...CallOptionFunc) (*InquiryResponse, error) {
+	if d.InquiryFunc != nil {
+		return d.InquiryFunc(ContextWithCallOptions(ctx, opts...), req)
+	}
+
+	return d.Next.Inquiry(ctx, req, opts...)
+}
+
+// Checkout calls CheckoutFunc or Next.Checkout
+func (d *Decorator) Checkout(ctx context.Context, req *OrderRequest, opts ...CallOptionFunc) (*OrderDetail, error) {
+	if d.CheckoutFunc != nil {
+		return d.CheckoutFunc(ContextWithCallOptions(ctx, opts...), req)
+	}
+
+	return d.Next.Checkout(ctx, req, opts...)
+}
+
+// CheckStatus calls CheckStatusFunc or Next.CheckStatus
+func (d *Decorator) CheckStatus(ctx context.Context, requestID string, opts ...CallOptionFunc) (*OrderDetail, error) {
+	if d.CheckStatusFunc != nil {
+		return d.CheckStatusFunc(ContextWithCallOptions(ctx, opts...), requestID)
+	}
+
+	return d.Next.CheckStatus(ctx, requestID, opts...)
+}
+
+// ListProduct calls ListProductFunc or Next.ListProduct
+func (d *Decorator) ListProduct(ctx context.Context, code string, opts ...CallOptionFunc) ([]*Product, error) {
+	if d.ListProductFunc != nil {
+		return d.ListProductFunc(ContextWithCallOptions(ctx, opts...), code)
+	}
+
+	return d.Next.ListProduct(ctx, code, opts...)
+}
+
+// HandleCallback calls HandleCallbackFunc or Next.HandleCallback
+func (d *Decorator) HandleCallback(impl Callback, opts ...CallbackOptionFunc) http.HandlerFunc {
+	if d.HandleCallbackFunc != nil {
+		return d.HandleCallbackFunc(impl, opts...)
+	}
+
+	return d.Next.HandleCallback(impl, opts...)
+}
+
+// ObserverFunc receives the result of every operation, example to record metrics
+type ObserverFunc func(ctx context.Context, op Operation, duration time.Duration, err error)
+
+// Observe is a Middleware calling the observer after every operation
+func Observe(observer ObserverFunc) Middleware {
+	return func(next API) API {
+		observe := func(ctx context.Context, op Operation, start time.Time, err error) {
+			observer(ctx, op, time.Since(start), err)
+		}
+
+		return &Decorator{
+			Next: next,
+			PingFunc: func(ctx context.Context) (*PingResponse, error) {
+				start := time.Now()
+				resp, err := next.Ping(ctx)
+				observe(ctx, OperationPing, start, err)
+				return resp, err
+			},
+			AccountFunc: func(ctx context.Context) (*Account, error) {
+				start := time.Now()
+				resp, err := next.Account(ctx)
+				observe(ctx, OperationAccount, start, err)
+				return resp, err
+			},
+			InquiryFunc: func(ctx context.Context, req *InquiryRequest) (*InquiryResponse, error) {
+				start := time.Now()
+				resp, err := next.Inquiry(ctx, req)
+				observe(ctx, OperationInquiry, start, err)
+				return resp, err
+			},
+			CheckoutFunc: func(ctx context.Context, req *OrderRequest) (*OrderDetail, error) {
+				start := time.Now()
+				resp, err := next.Checkout(ctx, req)
+				observe(ctx, OperationCheckout, start, err)
+				return resp, err
+			},
+			CheckStatusFunc: func(ctx context.Context, requestID string) (*OrderDetail, error) {
+				start := time.Now()
+				resp, err := next.CheckStatus(ctx, requestID)
+				observe(ctx, OperationCheckStatus, start, err)
+				return resp, err
+			},
+			ListProductFunc: func(ctx context.Context, code string) ([]*Product, error) {
+				start := time.Now()
+				resp, err := next.ListProduct(ctx, code)
+				observe(ctx, OperationListProduct, start, err)
+				return resp, err
+			},
+		}
+	}
+}
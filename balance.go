@@ -0,0 +1,387 @@
+package sat
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// BalanceLevel is the level of balance compared with the thresholds
+type BalanceLevel int
+
+const (
+	// BalanceNormal is for balance above the warning threshold
+	BalanceNormal BalanceLevel = 0
+	// BalanceWarning is for balance below the warning threshold
+	BalanceWarning BalanceLevel = 1
+	// BalanceCritical is for balance below the critical threshold
+	BalanceCritical BalanceLevel = 2
+)
+
+// String returns the name of balance level
+func (l BalanceLevel) String() string {
+	switch l {
+	case BalanceNormal:
+		return "Normal"
+	case BalanceWarning:
+		return "Warning"
+	case BalanceCritical:
+		return "Critical"
+	default:
+		return "Unknown"
+	}
+}
+
+// BalanceAlert contains the balance level change
+type BalanceAlert struct {
+	Previous BalanceLevel
+	Level    BalanceLevel
+	Balance  int64
+	// Runway is the estimated time until the balance runs out, 0 when it can't be estimated yet
+	Runway time.Duration
+	At     time.Time
+}
+
+// BalanceHook receives every balance level change
+type BalanceHook func(ctx context.Context, alert BalanceAlert)
+
+// BalanceOption contains field you can configure on the balance monitor
+type BalanceOption struct {
+	logger            *log.Logger
+	pollInterval      time.Duration
+	warningThreshold  int64
+	criticalThreshold int64
+	hysteresis        int64
+	runwayWindow      time.Duration
+	checkoutGuard     ProductLookup
+}
+
+var defaultBalanceOption = BalanceOption{
+	logger:       log.New(log.Writer(), "[sat] ", 0),
+	pollInterval: time.Minute,
+	runwayWindow: time.Hour,
+}
+
+type BalanceOptionFunc func(*BalanceOption)
+
+// WithBalanceLogger override existing logger
+func WithBalanceLogger(logger *log.Logger) BalanceOptionFunc {
+	return func(o *BalanceOption) {
+		o.logger = logger
+	}
+}
+
+// WithPollInterval set how often the balance is fetched using Account, the default is used when it is not positive
+func WithPollInterval(pollInterval time.Duration) BalanceOptionFunc {
+	return func(o *BalanceOption) {
+		o.pollInterval = pollInterval
+	}
+}
+
+// WithBalanceThresholds set the warning and critical threshold, set 0 to disable the level
+func WithBalanceThresholds(warning, critical int64) BalanceOptionFunc {
+	return func(o *BalanceOption) {
+		o.warningThreshold = warning
+		o.criticalThreshold = critical
+	}
+}
+
+// WithHysteresis set how much the balance must recover above the threshold before the level goes back,
+// so the balance around the threshold doesn't fire the hooks repeatedly
+func WithHysteresis(hysteresis int64) BalanceOptionFunc {
+	return func(o *BalanceOption) {
+		o.hysteresis = hysteresis
+	}
+}
+
+// WithRunwayWindow set how far back the consumption is used to estimate the runway
+func WithRunwayWindow(runwayWindow time.Duration) BalanceOptionFunc {
+	return func(o *BalanceOption) {
+		o.runwayWindow = runwayWindow
+	}
+}
+
+// WithCheckoutGuard enable Middleware to block the checkout which the expected price exceeds the known balance,
+// the expected price is the amount of the request or the sales price of the product
+func WithCheckoutGuard(products ProductLookup) BalanceOptionFunc {
+	return func(o *BalanceOption) {
+		o.checkoutGuard = products
+	}
+}
+
+type balanceSample struct {
+	at    time.Time
+	saldo int64
+}
+
+// BalanceMonitor polls the balance using Account and fires the hooks when the balance level is changed
+type BalanceMonitor struct {
+	api API
+	opt BalanceOption
+
+	mu      sync.RWMutex
+	balance int64
+	known   bool
+	level   BalanceLevel
+	samples []balanceSample
+	hooks   []BalanceHook
+
+	stopOnce sync.Once
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// NewBalanceMonitor will return a new balance monitor, call Start to poll the balance
+func NewBalanceMonitor(api API, opts ...BalanceOptionFunc) *BalanceMonitor {
+	opt := defaultBalanceOption
+	for _, option := range opts {
+		option(&opt)
+	}
+
+	if opt.pollInterval <= 0 {
+		opt.pollInterval = defaultBalanceOption.pollInterval
+	}
+
+	return &BalanceMonitor{
+		api: api,
+		opt: opt,
+	}
+}
+
+// OnLevelChange registers the hook to receive every balance level change
+func (m *BalanceMonitor) OnLevelChange(hook BalanceHook) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.hooks = append(m.hooks, hook)
+}
+
+// Start fetches the balance and keeps polling it in background until Stop is called or ctx is done.
+// The error of the first fetch is returned, but the background polling is still started
+func (m *BalanceMonitor) Start(ctx context.Context) error {
+	err := m.Refresh(ctx)
+
+	ctx, m.cancel = context.WithCancel(ctx)
+	m.done = make(chan struct{})
+	go m.loop(ctx)
+
+	return err
+}
+
+// Stop stops the background polling
+func (m *BalanceMonitor) Stop() {
+	m.stopOnce.Do(func() {
+		if m.cancel == nil {
+			return
+		}
+
+		m.cancel()
+		<-m.done
+	})
+}
+
+func (m *BalanceMonitor) loop(ctx context.Context) {
+	defer close(m.done)
+
+	t := time.NewTicker(m.opt.pollInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		m.Refresh(ctx)
+	}
+}
+
+// Refresh fetches the balance using Account, the last known balance is kept when it is failed
+func (m *BalanceMonitor) Refresh(ctx context.Context) error {
+	account, err := m.api.Account(ctx)
+	if err != nil {
+		m.opt.logger.Println(err)
+		return err
+	}
+
+	m.update(ctx, account.Saldo, time.Now())
+	return nil
+}
+
+// Balance returns the last known balance, false when the balance is never fetched
+func (m *BalanceMonitor) Balance() (int64, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.balance, m.known
+}
+
+// Level returns the current balance level
+func (m *BalanceMonitor) Level() BalanceLevel {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.level
+}
+
+// Runway returns the estimated time until the balance runs out based on the consumption within the runway window,
+// false when there is no consumption yet
+func (m *BalanceMonitor) Runway() (time.Duration, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	runway := m.runway()
+	return runway, runway > 0
+}
+
+// Middleware will return a Middleware deducting the known balance after every successful checkout,
+// and blocking the checkout exceeding the known balance when WithCheckoutGuard is set
+func (m *BalanceMonitor) Middleware() Middleware {
+	return func(next API) API {
+		return &Decorator{
+			Next: next,
+			CheckoutFunc: func(ctx context.Context, req *OrderRequest) (*OrderDetail, error) {
+				if m.opt.checkoutGuard != nil {
+					err := m.guard(ctx, req)
+					if err != nil {
+						return nil, err
+					}
+				}
+
+				resp, err := next.Checkout(ctx, req)
+				if err != nil {
+					return nil, err
+				}
+
+				m.deduct(ctx, resp.SalesPrice)
+				return resp, nil
+			},
+		}
+	}
+}
+
+func (m *BalanceMonitor) guard(ctx context.Context, req *OrderRequest) error {
+	balance, known := m.Balance()
+	if !known {
+		return nil
+	}
+
+	price := req.Amount
+	if price <= 0 {
+		product, err := m.opt.checkoutGuard.Get(ctx, req.ProductCode)
+		if err != nil {
+			// let SAT decide when the price is unknown
+			m.opt.logger.Println(err)
+			return nil
+		}
+
+		price = product.SalesPrice
+	}
+
+	if price > balance {
+		return &InsufficientBalanceError{Balance: balance, Price: price}
+	}
+
+	return nil
+}
+
+func (m *BalanceMonitor) deduct(ctx context.Context, price int64) {
+	if price <= 0 {
+		return
+	}
+
+	// the balance is read and written under the same lock, so the concurrent deduction is not lost
+	m.change(ctx, time.Now(), func(balance int64, known bool) (int64, bool) {
+		return balance - price, known
+	})
+}
+
+func (m *BalanceMonitor) update(ctx context.Context, saldo int64, now time.Time) {
+	m.change(ctx, now, func(balance int64, known bool) (int64, bool) {
+		return saldo, true
+	})
+}
+
+// change sets the balance returned by next, next is called under the lock with the current balance,
+// nothing is changed when next returns false
+func (m *BalanceMonitor) change(ctx context.Context, now time.Time, next func(balance int64, known bool) (int64, bool)) {
+	m.mu.Lock()
+	saldo, ok := next(m.balance, m.known)
+	if !ok {
+		m.mu.Unlock()
+		return
+	}
+
+	m.balance = saldo
+	m.known = true
+
+	m.samples = append(m.samples, balanceSample{at: now, saldo: saldo})
+	i := 0
+	for i < len(m.samples)-1 && now.Sub(m.samples[i].at) > m.opt.runwayWindow {
+		i++
+	}
+	m.samples = m.samples[i:]
+
+	previous := m.level
+	m.level = m.nextLevel(previous, saldo)
+	alert := BalanceAlert{Previous: previous, Level: m.level, Balance: saldo, Runway: m.runway(), At: now}
+	hooks := m.hooks
+	m.mu.Unlock()
+
+	if alert.Level == alert.Previous {
+		return
+	}
+
+	for _, hook := range hooks {
+		hook(ctx, alert)
+	}
+}
+
+// nextLevel returns the level of the balance, the level goes back only when the balance is above the threshold plus hysteresis
+func (m *BalanceMonitor) nextLevel(current BalanceLevel, saldo int64) BalanceLevel {
+	level := m.levelOf(saldo, 0)
+	if level >= current {
+		return level
+	}
+
+	recovered := m.levelOf(saldo, m.opt.hysteresis)
+	if recovered < current {
+		return recovered
+	}
+
+	return current
+}
+
+func (m *BalanceMonitor) levelOf(saldo, margin int64) BalanceLevel {
+	switch {
+	case m.opt.criticalThreshold > 0 && saldo < m.opt.criticalThreshold+margin:
+		return BalanceCritical
+	case m.opt.warningThreshold > 0 && saldo < m.opt.warningThreshold+margin:
+		return BalanceWarning
+	default:
+		return BalanceNormal
+	}
+}
+
+// runway estimates from the decreases between the samples, the top up is ignored
+func (m *BalanceMonitor) runway() time.Duration {
+	if len(m.samples) < 2 || m.balance <= 0 {
+		return 0
+	}
+
+	var consumed int64
+	for i := 1; i < len(m.samples); i++ {
+		if d := m.samples[i-1].saldo - m.samples[i].saldo; d > 0 {
+			consumed += d
+		}
+	}
+
+	span := m.samples[len(m.samples)-1].at.Sub(m.samples[0].at)
+	if consumed == 0 || span <= 0 {
+		return 0
+	}
+
+	return time.Duration(float64(m.balance) / float64(consumed) * float64(span))
+}
@@ -0,0 +1,141 @@
+package sat
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBalanceMonitor_Level(t *testing.T) {
+	ctx := context.Background()
+	monitor := NewBalanceMonitor(nil,
+		WithBalanceLogger(log.New(io.Discard, "", 0)),
+		WithBalanceThresholds(100000, 50000),
+		WithHysteresis(10000),
+	)
+
+	var alerts []BalanceAlert
+	monitor.OnLevelChange(func(ctx context.Context, alert BalanceAlert) {
+		alerts = append(alerts, alert)
+	})
+
+	start := time.Now()
+	steps := []struct {
+		saldo int64
+		want  BalanceLevel
+	}{
+		{200000, BalanceNormal},
+		{99000, BalanceWarning},
+		{101000, BalanceWarning}, // within hysteresis
+		{99000, BalanceWarning},
+		{40000, BalanceCritical},
+		{55000, BalanceCritical}, // within hysteresis
+		{65000, BalanceWarning},
+		{120000, BalanceNormal},
+	}
+
+	for i, step := range steps {
+		monitor.update(ctx, step.saldo, start.Add(time.Duration(i)*time.Minute))
+		if got := monitor.Level(); got != step.want {
+			t.Errorf("step %d Level() got = %s, want %s", i, got, step.want)
+		}
+	}
+
+	if len(alerts) != 4 || alerts[1].Previous != BalanceWarning || alerts[1].Level != BalanceCritical {
+		t.Errorf("OnLevelChange() got = %v, want 4 alerts", alerts)
+	}
+}
+
+func TestBalanceMonitor_Runway(t *testing.T) {
+	ctx := context.Background()
+	monitor := NewBalanceMonitor(nil, WithRunwayWindow(time.Hour))
+
+	if _, ok := monitor.Runway(); ok {
+		t.Errorf("Runway() ok = true, want unknown without consumption")
+	}
+
+	start := time.Now()
+	monitor.update(ctx, 100000, start)
+	monitor.update(ctx, 90000, start.Add(10*time.Minute))
+	monitor.update(ctx, 200000, start.Add(15*time.Minute)) // top up is ignored
+	monitor.update(ctx, 190000, start.Add(20*time.Minute))
+
+	runway, ok := monitor.Runway()
+	if !ok || runway != 190*time.Minute {
+		t.Errorf("Runway() got = %v, %v, want %v", runway, ok, 190*time.Minute)
+	}
+}
+
+func TestBalanceMonitor_Middleware(t *testing.T) {
+	ctx := context.Background()
+	saldo := int64(15000)
+	next := &Decorator{
+		AccountFunc: func(ctx context.Context) (*Account, error) {
+			return &Account{Saldo: saldo}, nil
+		},
+		CheckoutFunc: func(ctx context.Context, req *OrderRequest) (*OrderDetail, error) {
+			return &OrderDetail{RequestID: req.RequestID, SalesPrice: 10200}, nil
+		},
+	}
+
+	stub := &listProductStub{products: []*Product{{Code: "telkomsel-10k", SalesPrice: 10200}}}
+	catalog := NewProductCatalog(stub.api())
+	catalog.Refresh(ctx)
+
+	monitor := NewBalanceMonitor(next, WithCheckoutGuard(catalog), WithPollInterval(time.Hour))
+	err := monitor.Start(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer monitor.Stop()
+
+	api := Chain(next, monitor.Middleware())
+	_, err = api.Checkout(ctx, &OrderRequest{RequestID: "order-1", ProductCode: "telkomsel-10k"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if balance, _ := monitor.Balance(); balance != 4800 {
+		t.Errorf("Balance() got = %d, want deducted balance", balance)
+	}
+
+	_, err = api.Checkout(ctx, &OrderRequest{RequestID: "order-2", ProductCode: "telkomsel-10k"})
+	var errB *InsufficientBalanceError
+	if !errors.As(err, &errB) || errB.Balance != 4800 || errB.Price != 10200 {
+		t.Errorf("Checkout() error = %v, want insufficient balance", err)
+	}
+}
+
+func TestBalanceMonitor_deduct(t *testing.T) {
+	ctx := context.Background()
+	monitor := NewBalanceMonitor(&Decorator{
+		AccountFunc: func(ctx context.Context) (*Account, error) {
+			return &Account{Saldo: 1000000}, nil
+		},
+	}, WithPollInterval(0))
+
+	// the zero poll interval falls back to the default instead of panic
+	err := monitor.Start(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer monitor.Stop()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			monitor.deduct(ctx, 1000)
+		}()
+	}
+	wg.Wait()
+
+	if balance, _ := monitor.Balance(); balance != 950000 {
+		t.Errorf("Balance() got = %d, want every deduction kept", balance)
+	}
+}
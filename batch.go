@@ -0,0 +1,284 @@
+package sat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/tokopedia/golang-sat/internal/jsonl"
+	"github.com/tokopedia/golang-sat/internal/pool"
+)
+
+// ErrBatchStopped is the result error of the order not submitted because the batch is stopped by the balance error
+var ErrBatchStopped = errors.New(BATCH_STOPPED)
+
+// BatchOptions contains field you can configure on the batch
+type BatchOptions struct {
+	// Concurrency is the max in-flight Checkout, default 4
+	Concurrency int
+	// RateLimit is the max Checkout per second, 0 is unlimited
+	RateLimit float64
+	// StopOnBalanceError stops submitting the remaining orders after the balance error,
+	// the remaining orders get ErrBatchStopped
+	StopOnBalanceError bool
+	// BalanceErrorCodes are the SAT error codes treated as the balance error, *InsufficientBalanceError always is
+	BalanceErrorCodes []string
+	// Progress is called after every order is done, the calls are serialized
+	Progress func(progress BatchProgress)
+	// Checkpoint is the file path where every answered order is appended, the orders already
+	// on the checkpoint are not submitted again when the batch is resumed, except the balance error.
+	// Every RequestID must be set
+	Checkpoint string
+	// Logger is used to log the failed checkpoint write
+	Logger *log.Logger
+}
+
+// BatchResult contains the result of an order, either Order or Err is set
+type BatchResult struct {
+	Index   int
+	Request *OrderRequest
+	Order   *OrderDetail
+	Err     error
+	// Restored is true when the result is loaded from the checkpoint
+	Restored bool
+}
+
+// BatchProgress contains the batch progress after an order is done
+type BatchProgress struct {
+	Total     int
+	Done      int
+	Succeeded int
+	Failed    int
+	Result    *BatchResult
+}
+
+// batchRecord is the checkpoint line of an answered order
+type batchRecord struct {
+	RequestID     string         `json:"request_id"`
+	Order         *OrderDetail   `json:"order,omitempty"`
+	ErrorResponse *ErrorResponse `json:"error_response,omitempty"`
+}
+
+// CheckoutBatch submits every order using Checkout of the client, see CheckoutBatch
+func (c *Client) CheckoutBatch(ctx context.Context, reqs []*OrderRequest, opts BatchOptions, callOpts ...CallOptionFunc) ([]*BatchResult, error) {
+	if opts.Logger == nil {
+		opts.Logger = c.logger
+	}
+
+	return CheckoutBatch(ctx, c, reqs, opts, callOpts...)
+}
+
+// CheckoutBatch submits every order using the worker pool and returns the result of every order in the order of reqs.
+// When ctx is done the orders not submitted get ctx error and ctx error is returned,
+// call CheckoutBatch again with the same checkpoint to resume. The call options are applied to every Checkout
+func CheckoutBatch(ctx context.Context, api API, reqs []*OrderRequest, opts BatchOptions, callOpts ...CallOptionFunc) ([]*BatchResult, error) {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 4
+	}
+
+	if opts.Logger == nil {
+		opts.Logger = log.New(log.Writer(), "[sat] ", 0)
+	}
+
+	if opts.Checkpoint != "" {
+		verr := &ValidationError{}
+		for i, req := range reqs {
+			if req.RequestID == "" {
+				verr.add(fmt.Sprintf("requests[%d].request_id", i), "can't be empty when checkpoint is set")
+			}
+		}
+
+		if err := verr.err(); err != nil {
+			return nil, err
+		}
+	}
+
+	done := map[string]*batchRecord{}
+	var checkpoint *jsonl.File
+	if opts.Checkpoint != "" {
+		err := jsonl.Load(opts.Checkpoint, func() interface{} { return new(batchRecord) }, func(v interface{}) {
+			record := v.(*batchRecord)
+			done[record.RequestID] = record
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		checkpoint, err = jsonl.Open(opts.Checkpoint)
+		if err != nil {
+			return nil, err
+		}
+		defer checkpoint.Close()
+	}
+
+	b := &batch{
+		api:      api,
+		opts:     opts,
+		reqs:     reqs,
+		results:  make([]*BatchResult, len(reqs)),
+		limiter:  pool.NewBucket(opts.RateLimit, 1),
+		progress: BatchProgress{Total: len(reqs)},
+		file:     checkpoint,
+		callOpts: callOpts,
+		callOpt:  newCallOption(ctx, callOpts),
+	}
+
+	for i, req := range reqs {
+		record, ok := done[req.RequestID]
+		if !ok || req.RequestID == "" {
+			continue
+		}
+
+		result := &BatchResult{Index: i, Request: req, Order: record.Order, Restored: true}
+		if record.ErrorResponse != nil {
+			result.Err = record.ErrorResponse
+		}
+
+		b.results[i] = result
+		b.count(result)
+	}
+
+	return b.run(ctx)
+}
+
+type batch struct {
+	api     API
+	opts    BatchOptions
+	reqs    []*OrderRequest
+	results []*BatchResult
+	limiter *pool.Bucket
+
+	mu       sync.Mutex
+	progress BatchProgress
+	stopped  bool
+	file     *jsonl.File
+	callOpts []CallOptionFunc
+	callOpt  *CallOption
+}
+
+func (b *batch) run(ctx context.Context) ([]*BatchResult, error) {
+	var pending []int
+	for i := range b.reqs {
+		if b.results[i] == nil {
+			pending = append(pending, i)
+		}
+	}
+
+	// the rate limit is waited by checkout, so the stopped batch doesn't wait for it
+	pool.Run(ctx, pending, b.opts.Concurrency, nil, func(i int) {
+		b.finish(b.checkout(ctx, i))
+	})
+
+	for i, req := range b.reqs {
+		if b.results[i] == nil {
+			b.finish(&BatchResult{Index: i, Request: req, Err: ctx.Err()})
+		}
+	}
+
+	return b.results, ctx.Err()
+}
+
+func (b *batch) checkout(ctx context.Context, i int) *BatchResult {
+	result := &BatchResult{Index: i, Request: b.reqs[i]}
+	if b.isStopped() {
+		result.Err = ErrBatchStopped
+		return result
+	}
+
+	err := b.limiter.Wait(ctx)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	// the stop can happen while waiting for the rate limit
+	if b.isStopped() {
+		result.Err = ErrBatchStopped
+		return result
+	}
+
+	result.Order, result.Err = b.api.Checkout(ctx, b.reqs[i], b.callOpts...)
+	if b.opts.StopOnBalanceError && b.isBalanceError(result.Err) {
+		b.mu.Lock()
+		b.stopped = true
+		b.mu.Unlock()
+	}
+
+	return result
+}
+
+func (b *batch) isStopped() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.stopped
+}
+
+func (b *batch) isBalanceError(err error) bool {
+	var balanceErr *InsufficientBalanceError
+	if errors.As(err, &balanceErr) {
+		return true
+	}
+
+	var errR *ErrorResponse
+	if !errors.As(err, &errR) {
+		return false
+	}
+
+	for _, code := range b.opts.BalanceErrorCodes {
+		if errR.Code() == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// finish keeps the result, appends the answered order to the checkpoint and reports the progress
+func (b *batch) finish(result *BatchResult) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.results[result.Index] = result
+	b.count(result)
+	b.save(result)
+
+	if b.opts.Progress != nil {
+		progress := b.progress
+		progress.Result = result
+		b.opts.Progress(progress)
+	}
+}
+
+func (b *batch) count(result *BatchResult) {
+	b.progress.Done++
+	if result.Err != nil {
+		b.progress.Failed++
+		return
+	}
+
+	b.progress.Succeeded++
+}
+
+// save appends the created or rejected order. The order without answer, with server error, 429
+// or rejected by the balance error is submitted again on resume
+func (b *batch) save(result *BatchResult) {
+	if b.file == nil || b.isBalanceError(result.Err) {
+		return
+	}
+
+	// the server error or 429 doesn't tell the final outcome, it is submitted again with the same RequestID on resume
+	if result.Err != nil && !isRejected(result.Err) {
+		return
+	}
+
+	record := &batchRecord{RequestID: result.Request.RequestID, Order: result.Order}
+	errors.As(result.Err, &record.ErrorResponse)
+
+	err := b.file.Append(record)
+	if err != nil {
+		b.callOpt.log(b.opts.Logger, err)
+	}
+}
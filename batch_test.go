@@ -0,0 +1,137 @@
+package sat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestCheckoutBatch(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "batch.jsonl")
+
+	var mu sync.Mutex
+	calls := map[string]int{}
+	balance := int64(30000)
+	next := &Decorator{
+		CheckoutFunc: func(ctx context.Context, req *OrderRequest) (*OrderDetail, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			calls[req.RequestID]++
+			switch {
+			case req.ProductCode == "unknown":
+				return nil, &ErrorResponse{Errors: []*ErrorObject{{Status: "400", Code: "P01", Detail: "product is not found"}}}
+			case req.ProductCode == "throttled" && calls[req.RequestID] == 1:
+				return nil, &ErrorResponse{Errors: []*ErrorObject{{Status: "429", Code: "R00", Detail: "too many requests"}}}
+			case balance < 10000:
+				return nil, &ErrorResponse{Errors: []*ErrorObject{{Status: "400", Code: "B01", Detail: "insufficient balance"}}}
+			}
+
+			balance -= 10000
+			return &OrderDetail{RequestID: req.RequestID, Status: OrderStatusPending, Fields: Fields{{Name: "ref_id", Value: req.RequestID}}}, nil
+		},
+	}
+
+	var reqs []*OrderRequest
+	for i := 0; i < 7; i++ {
+		reqs = append(reqs, &OrderRequest{RequestID: fmt.Sprintf("order-%d", i), ProductCode: "telkomsel-10k"})
+	}
+	reqs[1].ProductCode = "unknown"
+	reqs[2].ProductCode = "throttled"
+
+	var last BatchProgress
+	opts := BatchOptions{
+		Concurrency:        1,
+		RateLimit:          1000,
+		StopOnBalanceError: true,
+		BalanceErrorCodes:  []string{"B01"},
+		Progress:           func(progress BatchProgress) { last = progress },
+		Checkpoint:         path,
+		Logger:             log.New(io.Discard, "", 0),
+	}
+
+	results, err := CheckoutBatch(ctx, next, reqs, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var errR *ErrorResponse
+	switch {
+	case results[0].Err != nil || results[0].Order.RequestID != "order-0":
+		t.Errorf("CheckoutBatch() result 0 got = %v, %v", results[0].Order, results[0].Err)
+	case !errors.As(results[1].Err, &errR) || errR.Code() != "P01":
+		t.Errorf("CheckoutBatch() result 1 error = %v, want P01", results[1].Err)
+	case !errors.As(results[2].Err, &errR) || errR.Code() != "R00":
+		t.Errorf("CheckoutBatch() result 2 error = %v, want R00", results[2].Err)
+	case !errors.As(results[5].Err, &errR) || errR.Code() != "B01":
+		t.Errorf("CheckoutBatch() result 5 error = %v, want B01", results[5].Err)
+	case !errors.Is(results[6].Err, ErrBatchStopped):
+		t.Errorf("CheckoutBatch() result 6 error = %v, want %v", results[6].Err, ErrBatchStopped)
+	}
+
+	if last.Total != 7 || last.Done != 7 || last.Succeeded != 3 || last.Failed != 4 {
+		t.Errorf("Progress got = %+v", last)
+	}
+
+	// the half written line is ignored on resume
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	f.Write([]byte(`{"request_id":"order-6","ord`))
+	f.Close()
+
+	// the 429 order is submitted again with the balance and the stopped orders
+	mu.Lock()
+	balance = 30000
+	mu.Unlock()
+
+	results, err = CheckoutBatch(ctx, next, reqs, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []int{1, 1, 2, 1, 1, 2, 1} {
+		if got := calls[reqs[i].RequestID]; got != want {
+			t.Errorf("Checkout(%s) called %d times, want %d", reqs[i].RequestID, got, want)
+		}
+	}
+
+	if !results[0].Restored || results[0].Order.Fields[0].Value != "order-0" {
+		t.Errorf("CheckoutBatch() resumed result 0 got = %+v", results[0])
+	}
+
+	if !errors.As(results[1].Err, &errR) || errR.Code() != "P01" {
+		t.Errorf("CheckoutBatch() resumed result 1 error = %v, want P01", results[1].Err)
+	}
+
+	if results[2].Err != nil || results[5].Err != nil || results[6].Err != nil {
+		t.Errorf("CheckoutBatch() resumed errors = %v, %v, %v", results[2].Err, results[5].Err, results[6].Err)
+	}
+}
+
+func TestCheckoutBatch_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	next := &Decorator{
+		CheckoutFunc: func(ctx context.Context, req *OrderRequest) (*OrderDetail, error) {
+			return &OrderDetail{RequestID: req.RequestID}, nil
+		},
+	}
+
+	results, err := CheckoutBatch(ctx, next, []*OrderRequest{{RequestID: "order-1"}}, BatchOptions{})
+	if !errors.Is(err, context.Canceled) || len(results) != 1 || !errors.Is(results[0].Err, context.Canceled) {
+		t.Errorf("CheckoutBatch() got = %v, %v, want %v", results, err, context.Canceled)
+	}
+
+	_, err = CheckoutBatch(context.Background(), next, []*OrderRequest{{}}, BatchOptions{Checkpoint: "batch.jsonl"})
+	var verr *ValidationError
+	if !errors.As(err, &verr) || !verr.Has("requests[0].request_id") {
+		t.Errorf("CheckoutBatch() error = %v, want empty request id", err)
+	}
+}
@@ -0,0 +1,350 @@
+package sat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is matched by errors.Is when the call is rejected by the open circuit, see *CircuitOpenError
+var ErrCircuitOpen = errors.New(CIRCUIT_OPEN)
+
+// CircuitOpenError is returned immediately without calling SAT while the circuit of the operation is open
+type CircuitOpenError struct {
+	Operation Operation
+	// RetryAt is when the circuit becomes half open and lets the trial calls through
+	RetryAt time.Time
+}
+
+// Error will return circuit open message with the operation
+func (c *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%s - %s until %s", CIRCUIT_OPEN, c.Operation, c.RetryAt.Format(time.RFC3339))
+}
+
+// Is will return true for ErrCircuitOpen
+func (c *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// CircuitState is the state of the circuit breaker
+type CircuitState int
+
+const (
+	// CircuitClosed is for circuit letting every call through
+	CircuitClosed CircuitState = 0
+	// CircuitOpen is for circuit rejecting every call
+	CircuitOpen CircuitState = 1
+	// CircuitHalfOpen is for circuit letting the trial calls through after the open timeout
+	CircuitHalfOpen CircuitState = 2
+)
+
+// String returns the name of circuit state
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "Closed"
+	case CircuitOpen:
+		return "Open"
+	case CircuitHalfOpen:
+		return "HalfOpen"
+	default:
+		return "Unknown"
+	}
+}
+
+// CircuitStateChange contains the state change of the circuit of an operation
+type CircuitStateChange struct {
+	Operation Operation
+	Previous  CircuitState
+	State     CircuitState
+	// Err is the failure opening the circuit
+	Err error
+	At  time.Time
+}
+
+// CircuitHook receives every circuit state change
+type CircuitHook func(change CircuitStateChange)
+
+// CircuitBreakerOption contains field you can configure on the circuit breaker
+type CircuitBreakerOption struct {
+	failureThreshold int
+	failureRate      float64
+	minRequests      int
+	failureWindow    time.Duration
+	openTimeout      time.Duration
+	halfOpenRequests int
+	failureCodes     []string
+}
+
+var defaultCircuitBreakerOption = CircuitBreakerOption{
+	failureThreshold: 5,
+	minRequests:      20,
+	failureWindow:    time.Minute,
+	openTimeout:      30 * time.Second,
+	halfOpenRequests: 1,
+}
+
+type CircuitBreakerOptionFunc func(*CircuitBreakerOption)
+
+// WithFailureThreshold set how many consecutive failures open the circuit, 0 disables it
+func WithFailureThreshold(failureThreshold int) CircuitBreakerOptionFunc {
+	return func(o *CircuitBreakerOption) {
+		o.failureThreshold = failureThreshold
+	}
+}
+
+// WithFailureRate set the failure rate (0 to 1) opening the circuit when the window has at least minRequests calls
+func WithFailureRate(failureRate float64, minRequests int) CircuitBreakerOptionFunc {
+	return func(o *CircuitBreakerOption) {
+		o.failureRate = failureRate
+		o.minRequests = minRequests
+	}
+}
+
+// WithFailureWindow set the window of the failure rate
+func WithFailureWindow(failureWindow time.Duration) CircuitBreakerOptionFunc {
+	return func(o *CircuitBreakerOption) {
+		o.failureWindow = failureWindow
+	}
+}
+
+// WithOpenTimeout set how long the circuit stays open before the trial calls
+func WithOpenTimeout(openTimeout time.Duration) CircuitBreakerOptionFunc {
+	return func(o *CircuitBreakerOption) {
+		o.openTimeout = openTimeout
+	}
+}
+
+// WithHalfOpenRequests set how many successful trial calls close the circuit, only this many calls are let through at once
+func WithHalfOpenRequests(halfOpenRequests int) CircuitBreakerOptionFunc {
+	return func(o *CircuitBreakerOption) {
+		o.halfOpenRequests = halfOpenRequests
+	}
+}
+
+// WithFailureCodes set the SAT error codes counted as failure, the transport error, timeout and 5xx always are
+func WithFailureCodes(codes ...string) CircuitBreakerOptionFunc {
+	return func(o *CircuitBreakerOption) {
+		o.failureCodes = codes
+	}
+}
+
+type circuit struct {
+	state       CircuitState
+	consecutive int
+	windowStart time.Time
+	total       int
+	failed      int
+	openedAt    time.Time
+	trials      int
+	successes   int
+}
+
+// CircuitBreaker rejects the calls of the operation failing repeatedly, every operation has its own circuit.
+// The circuit opens on consecutive failures or failure rate, it becomes half open after the open timeout,
+// and it closes after the successful trial calls
+type CircuitBreaker struct {
+	opt CircuitBreakerOption
+
+	mu       sync.Mutex
+	circuits map[Operation]*circuit
+	hooks    []CircuitHook
+}
+
+// NewCircuitBreaker will return a new circuit breaker
+func NewCircuitBreaker(opts ...CircuitBreakerOptionFunc) *CircuitBreaker {
+	opt := defaultCircuitBreakerOption
+	for _, option := range opts {
+		option(&opt)
+	}
+
+	if opt.halfOpenRequests < 1 {
+		opt.halfOpenRequests = 1
+	}
+
+	return &CircuitBreaker{
+		opt:      opt,
+		circuits: map[Operation]*circuit{},
+	}
+}
+
+// OnStateChange registers the hook called after every state change, example to send the alert
+func (b *CircuitBreaker) OnStateChange(hook CircuitHook) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.hooks = append(b.hooks, hook)
+}
+
+// State will return the current state of the circuit of the operation
+func (b *CircuitBreaker) State(op Operation) CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.circuit(op)
+	if c.state == CircuitOpen && time.Since(c.openedAt) >= b.opt.openTimeout {
+		return CircuitHalfOpen
+	}
+
+	return c.state
+}
+
+// allow will return *CircuitOpenError when the call of the operation is rejected
+func (b *CircuitBreaker) allow(op Operation) error {
+	var changes []CircuitStateChange
+	defer func() { b.notify(changes) }()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.circuit(op)
+	now := time.Now()
+	if c.state == CircuitOpen && now.Sub(c.openedAt) >= b.opt.openTimeout {
+		changes = append(changes, b.transition(op, c, CircuitHalfOpen, nil, now))
+	}
+
+	switch c.state {
+	case CircuitOpen:
+		return &CircuitOpenError{Operation: op, RetryAt: c.openedAt.Add(b.opt.openTimeout)}
+	case CircuitHalfOpen:
+		if c.trials >= b.opt.halfOpenRequests {
+			return &CircuitOpenError{Operation: op, RetryAt: now}
+		}
+		c.trials++
+	}
+
+	return nil
+}
+
+// record counts the result of the call let through by allow
+func (b *CircuitBreaker) record(op Operation, err error) {
+	var changes []CircuitStateChange
+	defer func() { b.notify(changes) }()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.circuit(op)
+	now := time.Now()
+	failure := b.isFailure(err)
+	// the call cancelled by the caller tells nothing about SAT
+	ignored := errors.Is(err, context.Canceled)
+
+	switch c.state {
+	case CircuitHalfOpen:
+		if c.trials > 0 {
+			c.trials--
+		}
+
+		switch {
+		case ignored:
+		case failure:
+			changes = append(changes, b.transition(op, c, CircuitOpen, err, now))
+		default:
+			c.successes++
+			if c.successes >= b.opt.halfOpenRequests {
+				changes = append(changes, b.transition(op, c, CircuitClosed, nil, now))
+			}
+		}
+	case CircuitClosed:
+		if ignored {
+			return
+		}
+
+		if now.Sub(c.windowStart) >= b.opt.failureWindow {
+			c.windowStart, c.total, c.failed = now, 0, 0
+		}
+
+		c.total++
+		if !failure {
+			c.consecutive = 0
+			return
+		}
+
+		c.failed++
+		c.consecutive++
+		tripped := b.opt.failureThreshold > 0 && c.consecutive >= b.opt.failureThreshold
+		if b.opt.failureRate > 0 && c.total >= b.opt.minRequests && float64(c.failed)/float64(c.total) >= b.opt.failureRate {
+			tripped = true
+		}
+
+		if tripped {
+			changes = append(changes, b.transition(op, c, CircuitOpen, err, now))
+		}
+	}
+}
+
+// isFailure will return true for the transport error, timeout, 5xx and the failure codes
+func (b *CircuitBreaker) isFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var errR *ErrorResponse
+	if errors.As(err, &errR) {
+		if strings.HasPrefix(errR.Status(), "5") {
+			return true
+		}
+
+		for _, code := range b.opt.failureCodes {
+			if errR.Code() == code {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	var errI *InternalError
+	if errors.As(err, &errI) {
+		return errI.Response().StatusCode >= 500
+	}
+
+	var errN net.Error
+	return errors.Is(err, context.DeadlineExceeded) || errors.As(err, &errN)
+}
+
+func (b *CircuitBreaker) circuit(op Operation) *circuit {
+	c, ok := b.circuits[op]
+	if !ok {
+		c = &circuit{windowStart: time.Now()}
+		b.circuits[op] = c
+	}
+
+	return c
+}
+
+// transition changes the state and resets the counters, it must be called with the lock held
+func (b *CircuitBreaker) transition(op Operation, c *circuit, state CircuitState, err error, now time.Time) CircuitStateChange {
+	change := CircuitStateChange{Operation: op, Previous: c.state, State: state, Err: err, At: now}
+
+	c.state = state
+	c.consecutive, c.total, c.failed = 0, 0, 0
+	c.trials, c.successes = 0, 0
+	c.windowStart = now
+	if state == CircuitOpen {
+		c.openedAt = now
+	}
+
+	return change
+}
+
+func (b *CircuitBreaker) notify(changes []CircuitStateChange) {
+	if len(changes) == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	hooks := append([]CircuitHook(nil), b.hooks...)
+	b.mu.Unlock()
+
+	for _, change := range changes {
+		for _, hook := range hooks {
+			hook(change)
+		}
+	}
+}
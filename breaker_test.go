@@ -0,0 +1,107 @@
+package sat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_circuitBreaker(t *testing.T) {
+	var hits, healthy int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		if atomic.LoadInt32(&healthy) == 0 {
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		json.NewEncoder(w).Encode(&PingResponse{Status: "ok"})
+	}))
+	defer srv.Close()
+
+	breaker := NewCircuitBreaker(WithFailureThreshold(3), WithOpenTimeout(20*time.Millisecond))
+	var changes []CircuitStateChange
+	breaker.OnStateChange(func(change CircuitStateChange) {
+		changes = append(changes, change)
+	})
+
+	cln := &Client{
+		http:           srv.Client(),
+		logger:         log.New(io.Discard, "", 0),
+		satBaseURL:     srv.URL,
+		circuitBreaker: breaker,
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		cln.Ping(ctx)
+	}
+
+	_, err := cln.Ping(ctx)
+	var errC *CircuitOpenError
+	if !errors.Is(err, ErrCircuitOpen) || !errors.As(err, &errC) || errC.Operation != OperationPing {
+		t.Fatalf("Ping() error = %v, want %v", err, ErrCircuitOpen)
+	}
+
+	if hits != 3 || breaker.State(OperationPing) != CircuitOpen {
+		t.Errorf("Ping() hits = %d, state = %s, want 3 hits and open circuit", hits, breaker.State(OperationPing))
+	}
+
+	// the circuit of the other operation is still closed
+	if breaker.State(OperationAccount) != CircuitClosed {
+		t.Errorf("State(Account) got = %s, want %s", breaker.State(OperationAccount), CircuitClosed)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	atomic.StoreInt32(&healthy, 1)
+	_, err = cln.Ping(ctx)
+	if err != nil || breaker.State(OperationPing) != CircuitClosed {
+		t.Errorf("Ping() after open timeout error = %v, state = %s", err, breaker.State(OperationPing))
+	}
+
+	want := []CircuitState{CircuitOpen, CircuitHalfOpen, CircuitClosed}
+	if len(changes) != len(want) {
+		t.Fatalf("OnStateChange() got %d changes, want %d", len(changes), len(want))
+	}
+
+	for i, state := range want {
+		if changes[i].State != state {
+			t.Errorf("OnStateChange() change %d got = %s, want %s", i, changes[i].State, state)
+		}
+	}
+
+	if changes[0].Err == nil {
+		t.Errorf("OnStateChange() open change has no error")
+	}
+}
+
+func TestCircuitBreaker_failureRate(t *testing.T) {
+	breaker := NewCircuitBreaker(WithFailureThreshold(0), WithFailureRate(0.5, 4), WithFailureCodes("S00"))
+
+	rejected := &ErrorResponse{Errors: []*ErrorObject{{Status: "400", Code: "P01"}}}
+	supplier := &ErrorResponse{Errors: []*ErrorObject{{Status: "400", Code: "S00"}}}
+	for _, err := range []error{rejected, nil, supplier, context.Canceled} {
+		if breaker.allow(OperationInquiry) != nil {
+			t.Fatal("allow() rejected the call of the closed circuit")
+		}
+		breaker.record(OperationInquiry, err)
+	}
+
+	// 1 failure of 3 counted calls
+	if breaker.State(OperationInquiry) != CircuitClosed {
+		t.Fatalf("State() got = %s, want %s", breaker.State(OperationInquiry), CircuitClosed)
+	}
+
+	breaker.record(OperationInquiry, context.DeadlineExceeded)
+	if breaker.State(OperationInquiry) != CircuitOpen {
+		t.Errorf("State() after 2 failures of 4 calls got = %s, want %s", breaker.State(OperationInquiry), CircuitOpen)
+	}
+}
@@ -0,0 +1,290 @@
+package sat
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/jsonapi"
+)
+
+// Callback contains interface Handler the callback from the SAT
+type Callback interface {
+	Do(ctx context.Context, request *OrderDetail) error
+}
+
+// CallbackV2 contains interface Handler the callback from the SAT including its delivery metadata,
+// the handler decides the response sent back to SAT through CallbackResult
+type CallbackV2 interface {
+	Handle(ctx context.Context, event *CallbackEvent) CallbackResult
+}
+
+// CallbackFunc is an adapter to allow the use of ordinary functions as CallbackV2
+type CallbackFunc func(ctx context.Context, event *CallbackEvent) CallbackResult
+
+// Handle calls f(ctx, event)
+func (f CallbackFunc) Handle(ctx context.Context, event *CallbackEvent) CallbackResult {
+	return f(ctx, event)
+}
+
+// CallbackEvent contains the decoded callback payload and its delivery metadata,
+// it can be used to archive the evidence of the callback
+type CallbackEvent struct {
+	// Order is the decoded callback payload
+	Order *OrderDetail
+	// RawBody is the callback body as it is received
+	RawBody []byte
+	// Signature is the value of signature header
+	Signature string
+	// Header contains all the delivery headers
+	Header http.Header
+	// ReceivedAt is the time when the callback is received
+	ReceivedAt time.Time
+	// RemoteAddr is the network address that sent the callback
+	RemoteAddr string
+}
+
+// CallbackAction is the action decided by the callback handler
+type CallbackAction int
+
+const (
+	// CallbackAck acknowledges the callback, SAT will not send it again
+	CallbackAck CallbackAction = 0
+	// CallbackRetry asks SAT to send the callback again later
+	CallbackRetry CallbackAction = 1
+	// CallbackReject rejects the callback permanently
+	CallbackReject CallbackAction = 2
+)
+
+// CallbackResult contains the response sent back to SAT
+type CallbackResult struct {
+	Action CallbackAction
+	// Message is the response body, the default message will be used when it is empty
+	Message string
+}
+
+type callbackEventKey struct{}
+
+// CallbackEventFromContext returns the CallbackEvent of the callback being handled
+func CallbackEventFromContext(ctx context.Context) (*CallbackEvent, bool) {
+	event, ok := ctx.Value(callbackEventKey{}).(*CallbackEvent)
+	return event, ok
+}
+
+// MismatchHandler contains interface to handle a callback which doesn't agree with the order on CheckStatus.
+// The callback is always answered with 503 so SAT sends it again, example when CheckStatus is not updated yet.
+// The error returned by Do is only logged
+type MismatchHandler interface {
+	Do(ctx context.Context, mismatch *OrderMismatch) error
+}
+
+// OrderMismatch contains both payloads of an order which are not agree each other
+type OrderMismatch struct {
+	// Callback is the order detail sent by SAT through the callback
+	Callback *OrderDetail
+	// Status is the order detail returned by CheckStatus
+	Status *OrderDetail
+	// Fields contains the name of the fields which are not agree
+	Fields []string
+}
+
+// CallbackOption contains field you can configure on the callback handler
+type CallbackOption struct {
+	crossVerify     bool
+	mismatchHandler MismatchHandler
+}
+
+type CallbackOptionFunc func(*CallbackOption)
+
+// WithCrossVerification re-fetch the order using CheckStatus after the signature is verified,
+// Callback will be called only when both payloads are agree, otherwise the handler will be called
+func WithCrossVerification(handler MismatchHandler) CallbackOptionFunc {
+	return func(o *CallbackOption) {
+		o.crossVerify = true
+		o.mismatchHandler = handler
+	}
+}
+
+// VerifyCallback will verify the signature of the callback body and decode it.
+// Use this method when the callback is not received by HandleCallback, example: API gateway or message queue.
+// The error is either *InvalidSignatureError or *InvalidPayloadError
+func (c *Client) VerifyCallback(body []byte, headers http.Header) (*OrderDetail, error) {
+	err := c.signature.Verify(string(body), headers.Get(SIGNATURE_HEADER_KEY))
+	if err != nil {
+		c.logger.Println(err)
+		return nil, &InvalidSignatureError{err: err}
+	}
+
+	request := new(OrderDetail)
+	err = jsonapi.UnmarshalPayload(bytes.NewReader(body), request)
+	if err != nil {
+		c.logger.Println(err)
+		return nil, &InvalidPayloadError{err: err}
+	}
+
+	return request, nil
+}
+
+// CrossVerifyCallback will re-fetch the order using CheckStatus and compare it with the callback payload.
+// It returns nil mismatch when both payloads are agree. The call options are applied to CheckStatus
+func (c *Client) CrossVerifyCallback(ctx context.Context, request *OrderDetail, opts ...CallOptionFunc) (*OrderMismatch, error) {
+	status, err := c.CheckStatus(ctx, request.RequestID, opts...)
+	if err != nil {
+		newCallOption(ctx, opts).log(c.logger, err)
+		return nil, err
+	}
+
+	fields := compareOrderDetail(request, status)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	return &OrderMismatch{
+		Callback: request,
+		Status:   status,
+		Fields:   fields,
+	}, nil
+}
+
+// HandleCallback is method http.HandlerFunc to handle callback request from SAT
+// you can customize the implementation based on this interface Callback.
+// The error returned by Callback will reject the callback with the error message
+func (c *Client) HandleCallback(impl Callback, opts ...CallbackOptionFunc) http.HandlerFunc {
+	return c.HandleCallbackV2(CallbackFunc(func(ctx context.Context, event *CallbackEvent) CallbackResult {
+		err := impl.Do(ctx, event.Order)
+		if err != nil {
+			c.logger.Println(err)
+			return CallbackResult{Action: CallbackReject, Message: err.Error()}
+		}
+
+		return CallbackResult{Action: CallbackAck}
+	}), opts...)
+}
+
+// HandleCallbackV2 is method http.HandlerFunc to handle callback request from SAT
+// you can customize the implementation based on this interface CallbackV2
+func (c *Client) HandleCallbackV2(impl CallbackV2, opts ...CallbackOptionFunc) http.HandlerFunc {
+	opt := CallbackOption{}
+	for _, option := range opts {
+		option(&opt)
+	}
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		receivedAt := time.Now()
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			c.logger.Println(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		request, err := c.VerifyCallback(body, req.Header)
+		if err != nil {
+			var errS *InvalidSignatureError
+			w.WriteHeader(http.StatusBadRequest)
+			if errors.As(err, &errS) {
+				w.Write([]byte(INVALID_SIGNATURE))
+				return
+			}
+
+			w.Write([]byte(INVALID_PAYLOAD))
+			return
+		}
+
+		if opt.crossVerify && !c.crossVerify(w, req, &opt, request) {
+			return
+		}
+
+		event := &CallbackEvent{
+			Order:      request,
+			RawBody:    body,
+			Signature:  req.Header.Get(SIGNATURE_HEADER_KEY),
+			Header:     req.Header.Clone(),
+			ReceivedAt: receivedAt,
+			RemoteAddr: req.RemoteAddr,
+		}
+
+		ctx := context.WithValue(req.Context(), callbackEventKey{}, event)
+		writeCallbackResult(w, impl.Handle(ctx, event))
+		return
+	}
+}
+
+// writeCallbackResult will write the http response based on the callback action
+func writeCallbackResult(w http.ResponseWriter, result CallbackResult) {
+	status, message := http.StatusOK, SUCCESS_OK
+	switch result.Action {
+	case CallbackRetry:
+		status, message = http.StatusServiceUnavailable, CALLBACK_RETRY
+	case CallbackReject:
+		status, message = http.StatusBadRequest, CALLBACK_REJECTED
+	}
+
+	if result.Message != "" {
+		message = result.Message
+	}
+
+	w.WriteHeader(status)
+	w.Write([]byte(message))
+}
+
+// crossVerify will re-fetch the order and compare it with the callback payload.
+// It returns true when both payloads are agree, otherwise it writes the response by itself
+// and returns false
+func (c *Client) crossVerify(w http.ResponseWriter, req *http.Request, opt *CallbackOption, request *OrderDetail) bool {
+	mismatch, err := c.CrossVerifyCallback(req.Context(), request)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(CROSS_VERIFICATION_FAILED))
+		return false
+	}
+
+	if mismatch == nil {
+		return true
+	}
+
+	if opt.mismatchHandler != nil {
+		err = opt.mismatchHandler.Do(req.Context(), mismatch)
+		if err != nil {
+			c.logger.Println(err)
+		}
+	}
+
+	// the callback is not verified, it must not be acknowledged whatever the handler returns.
+	// CheckStatus can lag behind the callback, so SAT is asked to send it again instead of rejecting it
+	writeCallbackResult(w, CallbackResult{Action: CallbackRetry})
+	return false
+}
+
+// compareOrderDetail will return the name of the fields which are different between two orders
+func compareOrderDetail(a, b *OrderDetail) []string {
+	var fields []string
+	if a.Status != b.Status {
+		fields = append(fields, "status")
+	}
+
+	if a.ProductCode != b.ProductCode {
+		fields = append(fields, "product_code")
+	}
+
+	if a.ClientNumber != b.ClientNumber {
+		fields = append(fields, "client_number")
+	}
+
+	if a.SalesPrice != b.SalesPrice {
+		fields = append(fields, "sales_price")
+	}
+
+	if a.AdminFee != b.AdminFee {
+		fields = append(fields, "admin_fee")
+	}
+
+	if a.PartnerFee != b.PartnerFee {
+		fields = append(fields, "partner_fee")
+	}
+
+	return fields
+}
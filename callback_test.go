@@ -0,0 +1,358 @@
+package sat
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/jsonapi"
+	"github.com/tokopedia/golang-sat/signature"
+)
+
+type callbackRecorder struct {
+	called int
+}
+
+func (c *callbackRecorder) Do(ctx context.Context, request *OrderDetail) error {
+	c.called++
+	return nil
+}
+
+type mismatchRecorder struct {
+	mismatch *OrderMismatch
+}
+
+func (m *mismatchRecorder) Do(ctx context.Context, mismatch *OrderMismatch) error {
+	m.mismatch = mismatch
+	return nil
+}
+
+func newTestOauthServer() *httptest.Server {
+	return httptest.NewServer(func() http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			payload := `{
+					"access_token": "c:xxxxxxxxxxxxx",
+					"expires_in": 86400,
+					"token_type": "Bearer"
+				}`
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(200)
+			w.Write([]byte(payload))
+		}
+	}())
+}
+
+func newTestCheckStatusServer(payload *OrderDetail) *httptest.Server {
+	sgn := signature.Init(signature.Options{
+		PrivateKeyString: PrivateKeyDummy,
+		PublicKeyString:  PublicKeyDummy,
+	})
+
+	return httptest.NewServer(func() http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			if req.URL.Path != fmt.Sprintf(CHECK_STATUS_PATH, payload.RequestID) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			b := &bytes.Buffer{}
+			err := jsonapi.MarshalPayload(b, payload)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			signt, err := sgn.Sign(b.Bytes())
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set(SIGNATURE_HEADER_KEY, signt)
+			w.Write(b.Bytes())
+		}
+	}())
+}
+
+func sendTestCallback(t *testing.T, url string, payload *OrderDetail) (int, string) {
+	bd := &bytes.Buffer{}
+	err := jsonapi.MarshalPayload(bd, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sgn := signature.Init(signature.Options{
+		PrivateKeyString: PrivateKeyDummy,
+		PublicKeyString:  PublicKeyDummy,
+	})
+
+	signt, err := sgn.Sign(bd.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Add(SIGNATURE_HEADER_KEY, signt)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	res, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resp.StatusCode, string(res)
+}
+
+func TestClient_HandleCallbackCrossVerification(t *testing.T) {
+	callback := &OrderDetail{
+		RequestID:    "request_id",
+		ProductCode:  "pln-prepaid-token-100k",
+		ClientNumber: "102111106111",
+		Status:       "Success",
+		PartnerFee:   1000,
+		SalesPrice:   12000,
+		AdminFee:     2000,
+	}
+
+	tests := []struct {
+		name         string
+		status       OrderDetail
+		wantCode     int
+		wantBody     string
+		wantCalled   int
+		wantMismatch []string
+	}{
+		{
+			name:       "both payloads are agree",
+			status:     *callback,
+			wantCode:   http.StatusOK,
+			wantBody:   SUCCESS_OK,
+			wantCalled: 1,
+		},
+		{
+			name: "status and price are not agree",
+			status: func() OrderDetail {
+				o := *callback
+				o.Status = "Pending"
+				o.SalesPrice = 10000
+				return o
+			}(),
+			wantCode:     http.StatusServiceUnavailable,
+			wantBody:     CALLBACK_RETRY,
+			wantCalled:   0,
+			wantMismatch: []string{"status", "sales_price"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oauthServer := newTestOauthServer()
+			defer oauthServer.Close()
+
+			status := tt.status
+			satServer := newTestCheckStatusServer(&status)
+			defer satServer.Close()
+
+			cln, err := NewClient(
+				"abc",
+				"cde",
+				PrivateKeyDummy,
+				WithServerPublicKeyString(PublicKeyDummy),
+				WithHTTPClient(&http.Client{Timeout: 3 * time.Second}),
+				WithAccessTokenURL(oauthServer.URL+"/token"),
+				WithSatBaseURL(satServer.URL),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			clb := &callbackRecorder{}
+			mm := &mismatchRecorder{}
+			s := httptest.NewServer(cln.HandleCallback(clb, WithCrossVerification(mm)))
+			defer s.Close()
+
+			code, body := sendTestCallback(t, s.URL, callback)
+			if code != tt.wantCode || body != tt.wantBody {
+				t.Errorf("HandleCallback() got = %d %s, want %d %s", code, body, tt.wantCode, tt.wantBody)
+			}
+
+			if clb.called != tt.wantCalled {
+				t.Errorf("Callback.Do() called = %d, want %d", clb.called, tt.wantCalled)
+			}
+
+			if tt.wantMismatch == nil {
+				if mm.mismatch != nil {
+					t.Errorf("MismatchHandler.Do() called with %v", mm.mismatch.Fields)
+				}
+				return
+			}
+
+			if mm.mismatch == nil || fmt.Sprint(mm.mismatch.Fields) != fmt.Sprint(tt.wantMismatch) {
+				t.Errorf("MismatchHandler.Do() got = %v, want %v", mm.mismatch, tt.wantMismatch)
+			}
+		})
+	}
+}
+
+func TestClient_VerifyCallback(t *testing.T) {
+	cln, err := NewClient(
+		"abc",
+		"cde",
+		PrivateKeyDummy,
+		WithServerPublicKeyString(PublicKeyDummy),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sgn := signature.Init(signature.Options{
+		PrivateKeyString: PrivateKeyDummy,
+		PublicKeyString:  PublicKeyDummy,
+	})
+
+	payload := &OrderDetail{
+		RequestID:   "request_id",
+		ProductCode: "pln-prepaid-token-100k",
+		Status:      "Success",
+		SalesPrice:  12000,
+	}
+	bd := &bytes.Buffer{}
+	err = jsonapi.MarshalPayload(bd, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signBody := func(body []byte) http.Header {
+		signt, errS := sgn.Sign(body)
+		if errS != nil {
+			t.Fatal(errS)
+		}
+
+		return http.Header{http.CanonicalHeaderKey(SIGNATURE_HEADER_KEY): []string{signt}}
+	}
+
+	tests := []struct {
+		name    string
+		body    []byte
+		headers http.Header
+		want    *OrderDetail
+		wantErr interface{}
+	}{
+		{
+			name:    "valid callback",
+			body:    bd.Bytes(),
+			headers: signBody(bd.Bytes()),
+			want:    payload,
+		},
+		{
+			name:    "missing signature",
+			body:    bd.Bytes(),
+			headers: http.Header{},
+			wantErr: new(*InvalidSignatureError),
+		},
+		{
+			name:    "signed but invalid payload",
+			body:    []byte("{}"),
+			headers: signBody([]byte("{}")),
+			wantErr: new(*InvalidPayloadError),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cln.VerifyCallback(tt.body, tt.headers)
+			if tt.wantErr != nil {
+				if !errors.As(err, tt.wantErr) {
+					t.Errorf("VerifyCallback() error = %v, want %T", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("VerifyCallback() got = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_HandleCallbackV2(t *testing.T) {
+	cln, err := NewClient(
+		"abc",
+		"cde",
+		PrivateKeyDummy,
+		WithServerPublicKeyString(PublicKeyDummy),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := &OrderDetail{
+		RequestID:   "request_id",
+		ProductCode: "pln-prepaid-token-100k",
+		Status:      "Success",
+	}
+
+	tests := []struct {
+		name     string
+		result   CallbackResult
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "ack",
+			result:   CallbackResult{Action: CallbackAck},
+			wantCode: http.StatusOK,
+			wantBody: SUCCESS_OK,
+		},
+		{
+			name:     "retry later",
+			result:   CallbackResult{Action: CallbackRetry},
+			wantCode: http.StatusServiceUnavailable,
+			wantBody: CALLBACK_RETRY,
+		},
+		{
+			name:     "reject with message",
+			result:   CallbackResult{Action: CallbackReject, Message: "UNKNOWN_ORDER"},
+			wantCode: http.StatusBadRequest,
+			wantBody: "UNKNOWN_ORDER",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *CallbackEvent
+			s := httptest.NewServer(cln.HandleCallbackV2(CallbackFunc(func(ctx context.Context, event *CallbackEvent) CallbackResult {
+				fromCtx, ok := CallbackEventFromContext(ctx)
+				if !ok || fromCtx != event {
+					t.Errorf("CallbackEventFromContext() got = %v, want %v", fromCtx, event)
+				}
+
+				got = event
+				return tt.result
+			})))
+			defer s.Close()
+
+			code, body := sendTestCallback(t, s.URL, payload)
+			if code != tt.wantCode || body != tt.wantBody {
+				t.Errorf("HandleCallbackV2() got = %d %s, want %d %s", code, body, tt.wantCode, tt.wantBody)
+			}
+
+			if got == nil || got.Order.RequestID != payload.RequestID || got.Signature == "" ||
+				len(got.RawBody) == 0 || got.RemoteAddr == "" || got.ReceivedAt.IsZero() {
+				t.Errorf("CallbackEvent got = %+v", got)
+			}
+		})
+	}
+}
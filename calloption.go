@@ -0,0 +1,145 @@
+package sat
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CallOption contains field you can configure on a single call of the client
+type CallOption struct {
+	timeout          time.Duration
+	header           http.Header
+	correlationID    string
+	skipVerification bool
+	idempotencyKey   string
+}
+
+type CallOptionFunc func(*CallOption)
+
+// WithTimeout set the timeout of the call, it overrides the longer deadline of ctx
+func WithTimeout(timeout time.Duration) CallOptionFunc {
+	return func(o *CallOption) {
+		o.timeout = timeout
+	}
+}
+
+// WithHeader add the header to the request of the call
+func WithHeader(key, value string) CallOptionFunc {
+	return func(o *CallOption) {
+		o.header.Add(key, value)
+	}
+}
+
+// WithCorrelationID set X-Request-ID header of the request, and prefix the error logs of the call with the correlation id
+func WithCorrelationID(correlationID string) CallOptionFunc {
+	return func(o *CallOption) {
+		o.correlationID = correlationID
+	}
+}
+
+// WithSkipVerification skip the signature verification of the response, only for diagnostics
+func WithSkipVerification(skipVerification bool) CallOptionFunc {
+	return func(o *CallOption) {
+		o.skipVerification = skipVerification
+	}
+}
+
+// WithIdempotencyKey set Idempotency-Key header of the request
+func WithIdempotencyKey(idempotencyKey string) CallOptionFunc {
+	return func(o *CallOption) {
+		o.idempotencyKey = idempotencyKey
+	}
+}
+
+type callOptionsKey struct{}
+
+// ContextWithCallOptions will return a context carrying the call options, they are applied to every call using the context
+// before the options given to the call. Example to pass the correlation id of the gateway to every SAT request
+func ContextWithCallOptions(ctx context.Context, opts ...CallOptionFunc) context.Context {
+	if len(opts) == 0 {
+		return ctx
+	}
+
+	prev, _ := ctx.Value(callOptionsKey{}).([]CallOptionFunc)
+	merged := make([]CallOptionFunc, 0, len(prev)+len(opts))
+	merged = append(merged, prev...)
+	merged = append(merged, opts...)
+
+	return context.WithValue(ctx, callOptionsKey{}, merged)
+}
+
+// callOption applies the call options of ctx and opts, the returned ctx has the timeout of the call
+func callOption(ctx context.Context, opts []CallOptionFunc) (context.Context, *CallOption, context.CancelFunc) {
+	opt := newCallOption(ctx, opts)
+	if opt.timeout <= 0 {
+		return ctx, opt, func() {}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opt.timeout)
+	return ctx, opt, cancel
+}
+
+// newCallOption applies the call options of ctx and opts
+func newCallOption(ctx context.Context, opts []CallOptionFunc) *CallOption {
+	opt := &CallOption{header: http.Header{}}
+	prev, _ := ctx.Value(callOptionsKey{}).([]CallOptionFunc)
+	for _, option := range prev {
+		option(opt)
+	}
+
+	for _, option := range opts {
+		option(opt)
+	}
+
+	return opt
+}
+
+// key returns the applied options as string, the calls with the same key are sent with the same options
+func (o *CallOption) key() string {
+	names := make([]string, 0, len(o.header))
+	for name := range o.header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s|%s|%s|%t", o.timeout, o.correlationID, o.idempotencyKey, o.skipVerification)
+	for _, name := range names {
+		fmt.Fprintf(&b, "|%s=%q", name, o.header[name])
+	}
+
+	return b.String()
+}
+
+// apply sets the headers of the call to the request
+func (o *CallOption) apply(hreq *http.Request) {
+	for key, values := range o.header {
+		hreq.Header.Del(key)
+		for _, value := range values {
+			hreq.Header.Add(key, value)
+		}
+	}
+
+	if o.correlationID != "" {
+		hreq.Header.Set(CORRELATION_ID_HEADER_KEY, o.correlationID)
+	}
+
+	if o.idempotencyKey != "" {
+		hreq.Header.Set(IDEMPOTENCY_KEY_HEADER_KEY, o.idempotencyKey)
+	}
+}
+
+// log logs the error with the correlation id of the call
+func (o *CallOption) log(logger *log.Logger, err error) {
+	if o.correlationID == "" {
+		logger.Println(err)
+		return
+	}
+
+	logger.Println(o.correlationID, err)
+}
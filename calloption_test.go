@@ -0,0 +1,66 @@
+package sat
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/jsonapi"
+)
+
+func TestClient_callOptions(t *testing.T) {
+	headers := make(chan http.Header, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == PING_PATH:
+			headers <- r.Header.Clone()
+			w.Write([]byte("not json"))
+		case strings.HasPrefix(r.URL.Path, "/v2/order/slow"):
+			time.Sleep(100 * time.Millisecond)
+		default:
+			// the response is not signed
+			jsonapi.MarshalPayload(w, &OrderDetail{RequestID: "order-1", Status: OrderStatusSuccess})
+		}
+	}))
+	defer srv.Close()
+
+	var logs bytes.Buffer
+	cln := &Client{
+		http:       srv.Client(),
+		logger:     log.New(&logs, "", 0),
+		satBaseURL: srv.URL,
+	}
+
+	// the gateway correlation id is carried by ctx through the middleware
+	ctx := ContextWithCallOptions(context.Background(), WithCorrelationID("gateway-123"))
+	api := Chain(cln, Observe(func(ctx context.Context, op Operation, duration time.Duration, err error) {}))
+	_, err := api.Ping(ctx, WithHeader("X-Tenant", "acme"), WithIdempotencyKey("idem-1"))
+	if err == nil {
+		t.Fatal("Ping() error = nil, want invalid json error")
+	}
+
+	header := <-headers
+	if header.Get(CORRELATION_ID_HEADER_KEY) != "gateway-123" || header.Get("X-Tenant") != "acme" || header.Get(IDEMPOTENCY_KEY_HEADER_KEY) != "idem-1" {
+		t.Errorf("Ping() request header got = %v", header)
+	}
+
+	if !strings.HasPrefix(logs.String(), "gateway-123 ") {
+		t.Errorf("Ping() log got = %q, want the correlation id prefix", logs.String())
+	}
+
+	order, err := cln.CheckStatus(context.Background(), "order-1", WithSkipVerification(true))
+	if err != nil || order.Status != OrderStatusSuccess {
+		t.Errorf("CheckStatus() with skip verification got = %v, %v", order, err)
+	}
+
+	_, err = cln.CheckStatus(context.Background(), "slow", WithTimeout(10*time.Millisecond))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("CheckStatus() with timeout error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
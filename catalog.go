@@ -0,0 +1,328 @@
+package sat
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrProductNotFound is returned when the product code is not available on your credentials
+var ErrProductNotFound = errors.New(PRODUCT_NOT_FOUND)
+
+// CatalogOption contains field you can configure on the product catalog
+type CatalogOption struct {
+	logger          *log.Logger
+	refreshInterval time.Duration
+	retryInterval   time.Duration
+	missFallback    bool
+	missTTL         time.Duration
+	snapshotStore   SnapshotStore
+}
+
+var defaultCatalogOption = CatalogOption{
+	logger:          log.New(log.Writer(), "[sat] ", 0),
+	refreshInterval: 5 * time.Minute,
+	retryInterval:   30 * time.Second,
+	missFallback:    true,
+	missTTL:         time.Minute,
+}
+
+type CatalogOptionFunc func(*CatalogOption)
+
+// WithCatalogLogger override existing logger
+func WithCatalogLogger(logger *log.Logger) CatalogOptionFunc {
+	return func(o *CatalogOption) {
+		o.logger = logger
+	}
+}
+
+// WithRefreshInterval set how often the full catalog is reloaded in background, the default is used when it is not positive
+func WithRefreshInterval(refreshInterval time.Duration) CatalogOptionFunc {
+	return func(o *CatalogOption) {
+		o.refreshInterval = refreshInterval
+	}
+}
+
+// WithRetryInterval set how soon the full catalog is reloaded again after a failed refresh,
+// the last loaded catalog is still served meanwhile. The refresh interval is used when it is not positive
+func WithRetryInterval(retryInterval time.Duration) CatalogOptionFunc {
+	return func(o *CatalogOption) {
+		o.retryInterval = retryInterval
+	}
+}
+
+// WithMissFallback toggle ListProduct with the product code when the product is not found on the catalog
+func WithMissFallback(missFallback bool) CatalogOptionFunc {
+	return func(o *CatalogOption) {
+		o.missFallback = missFallback
+	}
+}
+
+// WithMissTTL set how long the product code not found by the miss fallback is not fetched again, 0 disables it
+func WithMissTTL(missTTL time.Duration) CatalogOptionFunc {
+	return func(o *CatalogOption) {
+		o.missTTL = missTTL
+	}
+}
+
+// ProductCatalog loads the full product list and serves the lookup by product code from memory.
+// The catalog is refreshed in background, and the last loaded catalog is served when the refresh is failed
+type ProductCatalog struct {
+	api API
+	opt CatalogOption
+
+	refreshMu   sync.Mutex
+	mu          sync.RWMutex
+	products    []*Product
+	index       map[string]*Product
+	misses      map[string]time.Time
+	snapshot    []*Product
+	hasSnapshot bool
+	subscribers []ProductSubscriber
+	refreshedAt time.Time
+	lastErr     error
+
+	stopOnce sync.Once
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// NewProductCatalog will return a new product catalog, call Start to load the catalog
+func NewProductCatalog(api API, opts ...CatalogOptionFunc) *ProductCatalog {
+	opt := defaultCatalogOption
+	for _, option := range opts {
+		option(&opt)
+	}
+
+	if opt.refreshInterval <= 0 {
+		opt.refreshInterval = defaultCatalogOption.refreshInterval
+	}
+
+	if opt.retryInterval <= 0 {
+		opt.retryInterval = opt.refreshInterval
+	}
+
+	return &ProductCatalog{
+		api:    api,
+		opt:    opt,
+		index:  map[string]*Product{},
+		misses: map[string]time.Time{},
+	}
+}
+
+// Start loads the full catalog and keeps refreshing it in background until Stop is called or ctx is done.
+// The error of the first load is returned, but the background refresh is still started.
+// When the snapshot store is set, the last known catalog is served until the first load is succeeded
+func (c *ProductCatalog) Start(ctx context.Context) error {
+	c.loadSnapshot(ctx)
+	err := c.Refresh(ctx)
+
+	ctx, c.cancel = context.WithCancel(ctx)
+	c.done = make(chan struct{})
+	go c.loop(ctx, err)
+
+	return err
+}
+
+// Stop stops the background refresh
+func (c *ProductCatalog) Stop() {
+	c.stopOnce.Do(func() {
+		if c.cancel == nil {
+			return
+		}
+
+		c.cancel()
+		<-c.done
+	})
+}
+
+func (c *ProductCatalog) loop(ctx context.Context, err error) {
+	defer close(c.done)
+
+	for {
+		interval := c.opt.refreshInterval
+		if err != nil && c.opt.retryInterval < interval {
+			interval = c.opt.retryInterval
+		}
+
+		t := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+
+		err = c.Refresh(ctx)
+	}
+}
+
+// Refresh reloads the full catalog using ListProduct, the current catalog is kept when it is failed.
+// The changes from the previous catalog are sent to every subscriber after the refresh is done,
+// so the subscriber can call Refresh
+func (c *ProductCatalog) Refresh(ctx context.Context) error {
+	events, subscribers, err := c.refresh(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		for _, subscriber := range subscribers {
+			subscriber(ctx, event)
+		}
+	}
+
+	return nil
+}
+
+// refresh reloads the full catalog and returns the changes with the subscribers to notify
+func (c *ProductCatalog) refresh(ctx context.Context) ([]ProductEvent, []ProductSubscriber, error) {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	products, err := c.api.ListProduct(ctx, "")
+	if err != nil {
+		c.opt.logger.Println(err)
+		c.mu.Lock()
+		c.lastErr = err
+		c.mu.Unlock()
+		return nil, nil, err
+	}
+
+	index := make(map[string]*Product, len(products))
+	for _, p := range products {
+		index[p.Code] = p
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	var events []ProductEvent
+	if c.hasSnapshot {
+		events = diffProducts(c.snapshot, products, now)
+	}
+
+	c.products = products
+	c.index = index
+	c.misses = map[string]time.Time{}
+	c.snapshot = products
+	c.hasSnapshot = true
+	c.refreshedAt = now
+	c.lastErr = nil
+	subscribers := c.subscribers
+	c.mu.Unlock()
+
+	if c.opt.snapshotStore != nil {
+		err = c.opt.snapshotStore.Save(ctx, products)
+		if err != nil {
+			c.opt.logger.Println(err)
+		}
+	}
+
+	return events, subscribers, nil
+}
+
+// loadSnapshot loads the last known catalog from the snapshot store
+func (c *ProductCatalog) loadSnapshot(ctx context.Context) {
+	if c.opt.snapshotStore == nil {
+		return
+	}
+
+	products, err := c.opt.snapshotStore.Load(ctx)
+	if err != nil {
+		c.opt.logger.Println(err)
+		return
+	}
+
+	if products == nil {
+		return
+	}
+
+	index := make(map[string]*Product, len(products))
+	for _, p := range products {
+		index[p.Code] = p
+	}
+
+	c.mu.Lock()
+	c.products = products
+	c.index = index
+	c.snapshot = products
+	c.hasSnapshot = true
+	c.mu.Unlock()
+}
+
+// Get returns the product from memory. When it is not found,
+// the product is fetched using ListProduct with the product code and kept until the next refresh.
+// The product code not found by SAT is remembered for the miss ttl
+func (c *ProductCatalog) Get(ctx context.Context, code string) (*Product, error) {
+	c.mu.RLock()
+	p, ok := c.index[code]
+	missExpiry, missed := c.misses[code]
+	c.mu.RUnlock()
+
+	if ok {
+		return p, nil
+	}
+
+	if !c.opt.missFallback || (missed && time.Now().Before(missExpiry)) {
+		return nil, ErrProductNotFound
+	}
+
+	products, err := c.api.ListProduct(ctx, code)
+	if errorStatus(err) == "404" {
+		c.miss(code)
+		return nil, ErrProductNotFound
+	}
+
+	if err != nil {
+		c.opt.logger.Println(err)
+		return nil, err
+	}
+
+	for _, p := range products {
+		if p.Code != code {
+			continue
+		}
+
+		c.mu.Lock()
+		if _, ok := c.index[code]; !ok {
+			c.index[code] = p
+			c.products = append(c.products[:len(c.products):len(c.products)], p)
+		}
+		c.mu.Unlock()
+
+		return p, nil
+	}
+
+	c.miss(code)
+	return nil, ErrProductNotFound
+}
+
+// miss remembers the product code not found by SAT
+func (c *ProductCatalog) miss(code string) {
+	if c.opt.missTTL <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.misses[code] = time.Now().Add(c.opt.missTTL)
+}
+
+// All returns every product on the catalog, the returned products must not be modified
+func (c *ProductCatalog) All() []*Product {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return append([]*Product(nil), c.products...)
+}
+
+// LastRefresh returns the time of the last successful refresh and the error of the last refresh
+func (c *ProductCatalog) LastRefresh() (time.Time, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.refreshedAt, c.lastErr
+}
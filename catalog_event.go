@@ -0,0 +1,166 @@
+package sat
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/google/jsonapi"
+)
+
+// ProductEventType is a type of product change
+type ProductEventType int
+
+const (
+	// ProductAdded is for product appears on the catalog
+	ProductAdded ProductEventType = 1
+	// ProductRemoved is for product disappears from the catalog
+	ProductRemoved ProductEventType = 2
+	// ProductPriceChanged is for product sales price changed
+	ProductPriceChanged ProductEventType = 3
+	// ProductStatusChanged is for product status changed, example active to temporary inactive
+	ProductStatusChanged ProductEventType = 4
+	// ProductInquiryChanged is for product inquiry flag changed
+	ProductInquiryChanged ProductEventType = 5
+)
+
+// String returns the name of product event type
+func (t ProductEventType) String() string {
+	switch t {
+	case ProductAdded:
+		return "ProductAdded"
+	case ProductRemoved:
+		return "ProductRemoved"
+	case ProductPriceChanged:
+		return "ProductPriceChanged"
+	case ProductStatusChanged:
+		return "ProductStatusChanged"
+	case ProductInquiryChanged:
+		return "ProductInquiryChanged"
+	default:
+		return "Unknown"
+	}
+}
+
+// ProductEvent contains a product change detected between two catalog snapshots
+type ProductEvent struct {
+	Type ProductEventType
+	Code string
+	// Old is the product on the previous snapshot, nil when the product is added
+	Old *Product
+	// New is the product on the current snapshot, nil when the product is removed
+	New        *Product
+	DetectedAt time.Time
+}
+
+// ProductSubscriber receives every product change detected by the catalog
+type ProductSubscriber func(ctx context.Context, event ProductEvent)
+
+// SnapshotStore persists the last known catalog, so changes across restarts are detected too
+type SnapshotStore interface {
+	// Load returns the last saved catalog, nil without error when nothing is saved yet
+	Load(ctx context.Context) ([]*Product, error)
+	Save(ctx context.Context, products []*Product) error
+}
+
+// FileSnapshotStore is a SnapshotStore saving the catalog as jsonapi payload into a file
+type FileSnapshotStore struct {
+	path string
+}
+
+// NewFileSnapshotStore will return a new snapshot store using the file path
+func NewFileSnapshotStore(path string) *FileSnapshotStore {
+	return &FileSnapshotStore{path: path}
+}
+
+// Load reads the catalog from the file
+func (f *FileSnapshotStore) Load(ctx context.Context) ([]*Product, error) {
+	b, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := jsonapi.UnmarshalManyPayload(bytes.NewReader(b), reflect.TypeOf(new(Product)))
+	if err != nil {
+		return nil, err
+	}
+
+	products := make([]*Product, 0, len(items))
+	for _, item := range items {
+		products = append(products, item.(*Product))
+	}
+
+	return products, nil
+}
+
+// Save writes the catalog into the file
+func (f *FileSnapshotStore) Save(ctx context.Context, products []*Product) error {
+	b := &bytes.Buffer{}
+	err := jsonapi.MarshalPayload(b, products)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(f.path, b.Bytes())
+}
+
+// WithSnapshotStore set the store persisting the last known catalog
+func WithSnapshotStore(store SnapshotStore) CatalogOptionFunc {
+	return func(o *CatalogOption) {
+		o.snapshotStore = store
+	}
+}
+
+// Subscribe registers the subscriber to receive every product change detected on refresh
+func (c *ProductCatalog) Subscribe(subscriber ProductSubscriber) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.subscribers = append(c.subscribers, subscriber)
+}
+
+// diffProducts returns the product changes from the previous snapshot to the current snapshot
+func diffProducts(previous, current []*Product, now time.Time) []ProductEvent {
+	old := make(map[string]*Product, len(previous))
+	for _, p := range previous {
+		old[p.Code] = p
+	}
+
+	var events []ProductEvent
+	seen := make(map[string]bool, len(current))
+	for _, p := range current {
+		seen[p.Code] = true
+		o, ok := old[p.Code]
+		if !ok {
+			events = append(events, ProductEvent{Type: ProductAdded, Code: p.Code, New: p, DetectedAt: now})
+			continue
+		}
+
+		if o.SalesPrice != p.SalesPrice {
+			events = append(events, ProductEvent{Type: ProductPriceChanged, Code: p.Code, Old: o, New: p, DetectedAt: now})
+		}
+
+		if o.Status != p.Status {
+			events = append(events, ProductEvent{Type: ProductStatusChanged, Code: p.Code, Old: o, New: p, DetectedAt: now})
+		}
+
+		if o.IsInquiry != p.IsInquiry {
+			events = append(events, ProductEvent{Type: ProductInquiryChanged, Code: p.Code, Old: o, New: p, DetectedAt: now})
+		}
+	}
+
+	for _, p := range previous {
+		if !seen[p.Code] {
+			events = append(events, ProductEvent{Type: ProductRemoved, Code: p.Code, Old: p, DetectedAt: now})
+		}
+	}
+
+	return events
+}
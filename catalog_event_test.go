@@ -0,0 +1,115 @@
+package sat
+
+import (
+	"context"
+	"io"
+	"log"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDiffProducts(t *testing.T) {
+	now := time.Now()
+	previous := []*Product{
+		{Code: "telkomsel-10k", SalesPrice: 10200, Status: ProductStatusActive},
+		{Code: "pln-postpaid", IsInquiry: false, Status: ProductStatusActive},
+		{Code: "xl-10k", SalesPrice: 10100, Status: ProductStatusActive},
+	}
+	current := []*Product{
+		{Code: "telkomsel-10k", SalesPrice: 10300, Status: ProductTempInactive},
+		{Code: "pln-postpaid", IsInquiry: true, Status: ProductStatusActive},
+		{Code: "indosat-10k", SalesPrice: 10000, Status: ProductStatusActive},
+	}
+
+	got := diffProducts(previous, current, now)
+	want := []struct {
+		typ  ProductEventType
+		code string
+	}{
+		{ProductPriceChanged, "telkomsel-10k"},
+		{ProductStatusChanged, "telkomsel-10k"},
+		{ProductInquiryChanged, "pln-postpaid"},
+		{ProductAdded, "indosat-10k"},
+		{ProductRemoved, "xl-10k"},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("diffProducts() got = %v, want %d events", got, len(want))
+	}
+
+	for i, w := range want {
+		if got[i].Type != w.typ || got[i].Code != w.code || !got[i].DetectedAt.Equal(now) {
+			t.Errorf("diffProducts()[%d] got = %s %s, want %s %s", i, got[i].Type, got[i].Code, w.typ, w.code)
+		}
+	}
+
+	if got[0].Old.SalesPrice != 10200 || got[0].New.SalesPrice != 10300 {
+		t.Errorf("diffProducts()[0] got = %v -> %v", got[0].Old, got[0].New)
+	}
+
+	if got[3].Old != nil || got[4].New != nil {
+		t.Errorf("diffProducts() added/removed got = %v, %v", got[3], got[4])
+	}
+}
+
+func TestProductCatalog_Subscribe(t *testing.T) {
+	ctx := context.Background()
+	store := NewFileSnapshotStore(filepath.Join(t.TempDir(), "catalog.json"))
+	stub := &listProductStub{
+		products: []*Product{
+			{Code: "telkomsel-10k", Name: "Telkomsel 10k", SalesPrice: 10200, Status: ProductStatusActive},
+		},
+	}
+
+	newCatalog := func() (*ProductCatalog, *[]ProductEvent) {
+		events := &[]ProductEvent{}
+		catalog := NewProductCatalog(stub.api(),
+			WithCatalogLogger(log.New(io.Discard, "", 0)),
+			WithRefreshInterval(time.Hour),
+			WithSnapshotStore(store),
+		)
+		catalog.Subscribe(func(ctx context.Context, event ProductEvent) {
+			*events = append(*events, event)
+		})
+
+		return catalog, events
+	}
+
+	catalog, events := newCatalog()
+	err := catalog.Start(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	catalog.Stop()
+
+	if len(*events) != 0 {
+		t.Errorf("first load got = %v, want no event", *events)
+	}
+
+	// the price is changed while the service is down
+	stub.products = []*Product{
+		{Code: "telkomsel-10k", Name: "Telkomsel 10k", SalesPrice: 10500, Status: ProductStatusActive},
+	}
+
+	catalog, events = newCatalog()
+	err = catalog.Start(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer catalog.Stop()
+
+	if len(*events) != 1 || (*events)[0].Type != ProductPriceChanged || (*events)[0].Old.SalesPrice != 10200 {
+		t.Errorf("restart got = %v, want price changed from the persisted snapshot", *events)
+	}
+
+	stub.products = nil
+	err = catalog.Refresh(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*events) != 2 || (*events)[1].Type != ProductRemoved {
+		t.Errorf("Refresh() got = %v, want product removed", *events)
+	}
+}
@@ -0,0 +1,361 @@
+package sat
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ProductSort is the order of the query result
+type ProductSort int
+
+const (
+	// SortNone keeps the order of the products, or by relevance when searching
+	SortNone ProductSort = 0
+	// SortByPrice sorts the products by SalesPrice
+	SortByPrice ProductSort = 1
+	// SortByDenomination sorts the products by the nominal parsed from the product code or name,
+	// the product without nominal is placed last
+	SortByDenomination ProductSort = 2
+)
+
+// QueryOption contains field you can configure on the product query
+type QueryOption struct {
+	categories []string
+	operators  []string
+	statuses   []ProductStatus
+	inquiry    *bool
+	minPrice   int64
+	maxPrice   int64
+	search     string
+	sortBy     ProductSort
+	descending bool
+}
+
+type QueryOptionFunc func(*QueryOption)
+
+// WithCategory filter the products by the category names, case insensitive
+func WithCategory(categories ...string) QueryOptionFunc {
+	return func(o *QueryOption) {
+		o.categories = append(o.categories, categories...)
+	}
+}
+
+// WithOperator filter the products by the operator names, case insensitive
+func WithOperator(operators ...string) QueryOptionFunc {
+	return func(o *QueryOption) {
+		o.operators = append(o.operators, operators...)
+	}
+}
+
+// WithStatus filter the products by the statuses
+func WithStatus(statuses ...ProductStatus) QueryOptionFunc {
+	return func(o *QueryOption) {
+		o.statuses = append(o.statuses, statuses...)
+	}
+}
+
+// WithInquiry filter the products by IsInquiry
+func WithInquiry(isInquiry bool) QueryOptionFunc {
+	return func(o *QueryOption) {
+		o.inquiry = &isInquiry
+	}
+}
+
+// WithPriceRange filter the products by SalesPrice inclusively, set max to 0 for no upper bound
+func WithPriceRange(min, max int64) QueryOptionFunc {
+	return func(o *QueryOption) {
+		o.minPrice = min
+		o.maxPrice = max
+	}
+}
+
+// WithSearch filter the products by the product name or code, typo is tolerated
+func WithSearch(search string) QueryOptionFunc {
+	return func(o *QueryOption) {
+		o.search = search
+	}
+}
+
+// WithSort set the order of the query result
+func WithSort(sortBy ProductSort, descending bool) QueryOptionFunc {
+	return func(o *QueryOption) {
+		o.sortBy = sortBy
+		o.descending = descending
+	}
+}
+
+// QueryProducts will return the products matching every filter, the given products are not modified.
+// It works on the result of ListProduct or ProductCatalog.All
+func QueryProducts(products []*Product, opts ...QueryOptionFunc) []*Product {
+	opt := QueryOption{}
+	for _, option := range opts {
+		option(&opt)
+	}
+
+	terms := searchTerms(opt.search)
+	scores := map[*Product]int{}
+
+	result := make([]*Product, 0, len(products))
+	for _, p := range products {
+		if !opt.match(p) {
+			continue
+		}
+
+		if len(terms) > 0 {
+			score, ok := searchScore(p, terms)
+			if !ok {
+				continue
+			}
+			scores[p] = score
+		}
+
+		result = append(result, p)
+	}
+
+	switch opt.sortBy {
+	case SortByPrice:
+		sort.SliceStable(result, func(i, j int) bool {
+			if opt.descending {
+				return result[i].SalesPrice > result[j].SalesPrice
+			}
+			return result[i].SalesPrice < result[j].SalesPrice
+		})
+	case SortByDenomination:
+		sort.SliceStable(result, func(i, j int) bool {
+			a, b := ProductDenomination(result[i]), ProductDenomination(result[j])
+			if a == 0 || b == 0 {
+				return b == 0 && a != 0
+			}
+			if opt.descending {
+				return a > b
+			}
+			return a < b
+		})
+	default:
+		if len(terms) > 0 {
+			sort.SliceStable(result, func(i, j int) bool {
+				return scores[result[i]] < scores[result[j]]
+			})
+		}
+	}
+
+	return result
+}
+
+// Query will return the products on the catalog matching every filter
+func (c *ProductCatalog) Query(opts ...QueryOptionFunc) []*Product {
+	return QueryProducts(c.All(), opts...)
+}
+
+func (o *QueryOption) match(p *Product) bool {
+	if len(o.categories) > 0 && !containsFold(o.categories, p.CategoryName) {
+		return false
+	}
+
+	if len(o.operators) > 0 && !containsFold(o.operators, p.OperatorName) {
+		return false
+	}
+
+	if len(o.statuses) > 0 {
+		found := false
+		for _, s := range o.statuses {
+			if s == p.Status {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if o.inquiry != nil && *o.inquiry != p.IsInquiry {
+		return false
+	}
+
+	if p.SalesPrice < o.minPrice || (o.maxPrice > 0 && p.SalesPrice > o.maxPrice) {
+		return false
+	}
+
+	return true
+}
+
+// ProductGroup contains the products sharing the same category or operator
+type ProductGroup struct {
+	Name     string
+	Products []*Product
+}
+
+// GroupByCategory will return the products grouped by CategoryName, sorted by the group name
+func GroupByCategory(products []*Product) []ProductGroup {
+	return groupProducts(products, func(p *Product) string { return p.CategoryName })
+}
+
+// GroupByOperator will return the products grouped by OperatorName, sorted by the group name
+func GroupByOperator(products []*Product) []ProductGroup {
+	return groupProducts(products, func(p *Product) string { return p.OperatorName })
+}
+
+func groupProducts(products []*Product, key func(p *Product) string) []ProductGroup {
+	index := map[string]int{}
+	var groups []ProductGroup
+	for _, p := range products {
+		name := key(p)
+		i, ok := index[name]
+		if !ok {
+			i = len(groups)
+			index[name] = i
+			groups = append(groups, ProductGroup{Name: name})
+		}
+
+		groups[i].Products = append(groups[i].Products, p)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].Name < groups[j].Name
+	})
+
+	return groups
+}
+
+var (
+	codeDenominationRegex = regexp.MustCompile(`^(\d+)(k|rb|jt)?$`)
+	nameDenominationRegex = regexp.MustCompile(`(?i)\b(\d{1,3}(?:\.\d{3})+|\d+)\s*(k|rb|ribu|jt|juta)?\b`)
+)
+
+// ProductDenomination will return the nominal parsed from the product code or name,
+// example 50000 for pln-prepaid-token-50k or Token PLN 50.000. It returns 0 when there is no nominal
+func ProductDenomination(p *Product) int64 {
+	for _, token := range strings.Split(strings.ToLower(p.Code), "-") {
+		m := codeDenominationRegex.FindStringSubmatch(token)
+		if m != nil {
+			return denomination(m[1], m[2])
+		}
+	}
+
+	m := nameDenominationRegex.FindStringSubmatch(p.Name)
+	if m != nil {
+		return denomination(strings.ReplaceAll(m[1], ".", ""), strings.ToLower(m[2]))
+	}
+
+	return 0
+}
+
+func denomination(number, unit string) int64 {
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	switch unit {
+	case "k", "rb", "ribu":
+		n *= 1000
+	case "jt", "juta":
+		n *= 1000000
+	}
+
+	return n
+}
+
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func searchTerms(search string) []string {
+	return strings.FieldsFunc(strings.ToLower(search), isSearchSeparator)
+}
+
+func isSearchSeparator(r rune) bool {
+	return r == ' ' || r == '-' || r == '_' || r == '.' || r == ','
+}
+
+// searchScore returns the total typo distance of every term to the product name or code,
+// the product is not matched when one of the terms is too far
+func searchScore(p *Product, terms []string) (int, bool) {
+	words := searchTerms(p.Name + " " + p.Code)
+
+	total := 0
+	for _, term := range terms {
+		best := -1
+		for _, word := range words {
+			d := 0
+			if !strings.HasPrefix(word, term) {
+				d = levenshtein(term, word)
+				if len(word) > len(term) {
+					// tolerate a typo on the prefix of a longer word
+					if pd := levenshtein(term, word[:len(term)]); pd < d {
+						d = pd
+					}
+				}
+			}
+
+			if best < 0 || d < best {
+				best = d
+			}
+		}
+
+		if best < 0 || best > typoTolerance(term) {
+			return 0, false
+		}
+
+		total += best
+	}
+
+	return total, true
+}
+
+func typoTolerance(term string) int {
+	switch {
+	case len(term) <= 3:
+		return 0
+	case len(term) <= 6:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			curr[j] = min3(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+
+	if c < a {
+		a = c
+	}
+
+	return a
+}
@@ -0,0 +1,119 @@
+package sat
+
+import (
+	"testing"
+)
+
+func testQueryProducts() []*Product {
+	return []*Product{
+		{Name: "Token PLN 100.000", Code: "pln-prepaid-token-100k", OperatorName: "PLN", CategoryName: "Listrik PLN", SalesPrice: 100500, Status: ProductStatusActive},
+		{Name: "Token PLN 20.000", Code: "pln-prepaid-token-20k", OperatorName: "PLN", CategoryName: "Listrik PLN", SalesPrice: 20500, Status: ProductStatusActive},
+		{Name: "Token PLN 50.000", Code: "pln-prepaid-token-50k", OperatorName: "PLN", CategoryName: "Listrik PLN", SalesPrice: 50500, Status: ProductTempInactive},
+		{Name: "Tagihan PLN", Code: "pln-postpaid", OperatorName: "PLN", CategoryName: "Listrik PLN", IsInquiry: true, Status: ProductStatusActive},
+		{Name: "Pulsa Telkomsel 10.000", Code: "telkomsel-10k", OperatorName: "Telkomsel", CategoryName: "Pulsa", SalesPrice: 10200, Status: ProductStatusActive},
+		{Name: "Pulsa Indosat 10.000", Code: "indosat-10k", OperatorName: "Indosat", CategoryName: "Pulsa", SalesPrice: 10100, Status: ProductStatusActive},
+	}
+}
+
+func codesOf(products []*Product) []string {
+	codes := make([]string, 0, len(products))
+	for _, p := range products {
+		codes = append(codes, p.Code)
+	}
+
+	return codes
+}
+
+func TestQueryProducts(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []QueryOptionFunc
+		want []string
+	}{
+		{
+			name: "active pln prepaid sorted by price",
+			opts: []QueryOptionFunc{
+				WithCategory("listrik pln"),
+				WithStatus(ProductStatusActive),
+				WithInquiry(false),
+				WithSort(SortByPrice, false),
+			},
+			want: []string{"pln-prepaid-token-20k", "pln-prepaid-token-100k"},
+		},
+		{
+			name: "price range descending",
+			opts: []QueryOptionFunc{WithPriceRange(10000, 60000), WithSort(SortByPrice, true)},
+			want: []string{"pln-prepaid-token-50k", "pln-prepaid-token-20k", "telkomsel-10k", "indosat-10k"},
+		},
+		{
+			name: "denomination",
+			opts: []QueryOptionFunc{WithOperator("PLN"), WithSort(SortByDenomination, false)},
+			want: []string{"pln-prepaid-token-20k", "pln-prepaid-token-50k", "pln-prepaid-token-100k", "pln-postpaid"},
+		},
+		{
+			name: "search with typo",
+			opts: []QueryOptionFunc{WithSearch("telkomsle")},
+			want: []string{"telkomsel-10k"},
+		},
+		{
+			name: "search by code and name",
+			opts: []QueryOptionFunc{WithSearch("pulsa 10k")},
+			want: []string{"telkomsel-10k", "indosat-10k"},
+		},
+		{
+			name: "search without match",
+			opts: []QueryOptionFunc{WithSearch("bpjs")},
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := codesOf(QueryProducts(testQueryProducts(), tt.opts...))
+			if len(got) != len(tt.want) {
+				t.Fatalf("QueryProducts() got = %v, want %v", got, tt.want)
+			}
+
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("QueryProducts() got = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestGroupByOperator(t *testing.T) {
+	pulsa := QueryProducts(testQueryProducts(), WithCategory("Pulsa"))
+	groups := GroupByOperator(pulsa)
+	if len(groups) != 2 || groups[0].Name != "Indosat" || groups[1].Name != "Telkomsel" {
+		t.Errorf("GroupByOperator() got = %v", groups)
+	}
+
+	groups = GroupByCategory(testQueryProducts())
+	if len(groups) != 2 || groups[0].Name != "Listrik PLN" || len(groups[0].Products) != 4 {
+		t.Errorf("GroupByCategory() got = %v", groups)
+	}
+}
+
+func TestProductDenomination(t *testing.T) {
+	tests := map[string]*Product{
+		"pln-prepaid-token-50k": {Code: "pln-prepaid-token-50k"},
+		"name with dot":         {Code: "telkomsel-data", Name: "Paket Data 25.000"},
+		"name with unit":        {Code: "ovo", Name: "OVO 1 jt"},
+		"without nominal":       {Code: "bpjs-kesehatan", Name: "BPJS Kesehatan"},
+	}
+	want := map[string]int64{
+		"pln-prepaid-token-50k": 50000,
+		"name with dot":         25000,
+		"name with unit":        1000000,
+		"without nominal":       0,
+	}
+
+	for name, p := range tests {
+		if got := ProductDenomination(p); got != want[name] {
+			t.Errorf("ProductDenomination(%s) got = %d, want %d", name, got, want[name])
+		}
+	}
+}
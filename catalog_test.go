@@ -0,0 +1,151 @@
+package sat
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"sync"
+	"testing"
+	"time"
+)
+
+type listProductStub struct {
+	mu       sync.Mutex
+	calls    []string
+	products []*Product
+	err      error
+}
+
+func (l *listProductStub) api() API {
+	return &Decorator{
+		ListProductFunc: func(ctx context.Context, code string) ([]*Product, error) {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+
+			l.calls = append(l.calls, code)
+			if l.err != nil {
+				return nil, l.err
+			}
+
+			if code == "" {
+				return l.products, nil
+			}
+
+			return []*Product{{Code: code, Name: "fetched " + code}}, nil
+		},
+	}
+}
+
+func TestProductCatalog(t *testing.T) {
+	ctx := context.Background()
+	stub := &listProductStub{
+		products: []*Product{
+			{Code: "pln-prepaid-token-50k", SalesPrice: 50500},
+			{Code: "telkomsel-10k", SalesPrice: 10200},
+		},
+	}
+
+	catalog := NewProductCatalog(stub.api(),
+		WithCatalogLogger(log.New(io.Discard, "", 0)),
+		WithRefreshInterval(time.Hour),
+	)
+	err := catalog.Start(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer catalog.Stop()
+
+	p, err := catalog.Get(ctx, "telkomsel-10k")
+	if err != nil || p.SalesPrice != 10200 {
+		t.Errorf("Get() got = %v, %v", p, err)
+	}
+
+	p, err = catalog.Get(ctx, "bpjs-kesehatan")
+	if err != nil || p.Name != "fetched bpjs-kesehatan" {
+		t.Errorf("Get() miss got = %v, %v", p, err)
+	}
+
+	_, err = catalog.Get(ctx, "bpjs-kesehatan")
+	if err != nil {
+		t.Errorf("Get() error = %v", err)
+	}
+
+	if len(stub.calls) != 2 || stub.calls[0] != "" || stub.calls[1] != "bpjs-kesehatan" {
+		t.Errorf("ListProduct() calls = %q, want full load and one miss", stub.calls)
+	}
+
+	stub.err = errors.New("sat is down")
+	err = catalog.Refresh(ctx)
+	if err == nil {
+		t.Errorf("Refresh() error = nil, want %v", stub.err)
+	}
+
+	if len(catalog.All()) != 3 {
+		t.Errorf("All() got = %d products, want the stale catalog", len(catalog.All()))
+	}
+
+	refreshedAt, lastErr := catalog.LastRefresh()
+	if refreshedAt.IsZero() || lastErr != stub.err {
+		t.Errorf("LastRefresh() got = %v, %v", refreshedAt, lastErr)
+	}
+}
+
+func TestProductCatalog_Miss(t *testing.T) {
+	ctx := context.Background()
+	calls := 0
+	api := &Decorator{
+		ListProductFunc: func(ctx context.Context, code string) ([]*Product, error) {
+			calls++
+			if code == "" {
+				return []*Product{{Code: "telkomsel-10k"}}, nil
+			}
+
+			return nil, &ErrorResponse{Errors: []*ErrorObject{{Status: "404", Code: "P01", Detail: "product is not found"}}}
+		},
+	}
+
+	catalog := NewProductCatalog(api, WithCatalogLogger(log.New(io.Discard, "", 0)), WithRefreshInterval(0), WithRetryInterval(0))
+	if catalog.opt.refreshInterval <= 0 || catalog.opt.retryInterval <= 0 {
+		t.Errorf("NewProductCatalog() intervals got = %v, %v, want positive", catalog.opt.refreshInterval, catalog.opt.retryInterval)
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err := catalog.Get(ctx, "unknown")
+		if !errors.Is(err, ErrProductNotFound) {
+			t.Errorf("Get() error = %v, want %v", err, ErrProductNotFound)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("ListProduct() called %d times, want the miss cached", calls)
+	}
+
+	// the subscriber is called outside of the refresh, so it can refresh again
+	refreshed := make(chan error, 1)
+	catalog.Subscribe(func(ctx context.Context, event ProductEvent) {
+		select {
+		case refreshed <- catalog.Refresh(ctx):
+		default:
+		}
+	})
+
+	err := catalog.Refresh(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = catalog.Get(ctx, "unknown")
+	if !errors.Is(err, ErrProductNotFound) || calls != 3 {
+		t.Errorf("Get() after refresh got = %v, called %d times, want the miss fetched again", err, calls)
+	}
+
+	api.ListProductFunc = func(ctx context.Context, code string) ([]*Product, error) {
+		return []*Product{{Code: "indosat-10k"}}, nil
+	}
+
+	err = catalog.Refresh(ctx)
+	if err != nil || <-refreshed != nil {
+		t.Errorf("Refresh() from the subscriber error = %v", err)
+	}
+}
@@ -31,11 +31,15 @@ type Client struct {
 	accessTokenURL string
 	signature      *signature.Signature
 	isDebug        bool
-}
+	inquiryTTL     time.Duration
+	inquiries      *inquiryClock
+
+	requestIDGenerator RequestIDGenerator
+	requestIDGuard     *requestIDGuard
+	rateLimiter        *RateLimiter
+	circuitBreaker     *CircuitBreaker
 
-// Callback contains interface Handler the callback from the SAT
-type Callback interface {
-	Do(ctx context.Context, request *OrderDetail) error
+	batchLimiter batchLimiter
 }
 
 // NewClient will return a new instance client
@@ -66,7 +70,7 @@ func NewClient(
 		option(&opt)
 	}
 
-	return &Client{
+	c := &Client{
 		http:           initHttpClient(&opt),
 		logger:         opt.logger,
 		satBaseURL:     opt.satBaseURL,
@@ -76,8 +80,19 @@ func NewClient(
 			PublicKeyString:  opt.serverPublicKey,
 			PaddingType:      opt.paddingType,
 		}),
-		isDebug: opt.isDebug,
-	}, nil
+		isDebug:    opt.isDebug,
+		inquiryTTL: opt.inquiryTTL,
+		inquiries:  &inquiryClock{},
+	}
+
+	c.requestIDGenerator = opt.requestIDGen
+	c.rateLimiter = opt.rateLimiter
+	c.circuitBreaker = opt.circuitBreaker
+	if opt.duplicateGuard > 0 {
+		c.requestIDGuard = newRequestIDGuard(opt.duplicateGuard)
+	}
+
+	return c, nil
 }
 
 func initHttpClient(
@@ -87,6 +102,10 @@ func initHttpClient(
 		cfg.http = &http.Client{}
 	}
 
+	// the token request is sent through the configured transport without the request logger,
+	// so the custom transport example a recorder also sees it, and the client secret is never logged
+	tokenClient := &http.Client{Transport: cfg.http.Transport, Timeout: cfg.http.Timeout}
+
 	logr := logger.Config{
 		Logger:  cfg.logger,
 		IsDebug: cfg.isDebug,
@@ -104,7 +123,7 @@ func initHttpClient(
 	}
 
 	cfg.http.Transport = &oauth2.Transport{
-		Source: cc.TokenSource(context.Background()),
+		Source: cc.TokenSource(context.WithValue(context.Background(), oauth2.HTTPClient, tokenClient)),
 		Base:   cfg.http.Transport,
 	}
 
@@ -112,64 +131,56 @@ func initHttpClient(
 }
 
 // Ping is a method to check the SAT server health
-func (c *Client) Ping(ctx context.Context) (*PingResponse, error) {
+func (c *Client) Ping(ctx context.Context, opts ...CallOptionFunc) (*PingResponse, error) {
+	ctx, opt, cancel := callOption(ctx, opts)
+	defer cancel()
+
 	hreq, err := http.NewRequestWithContext(ctx, http.MethodGet, c.satBaseURL+PING_PATH, nil)
 	if err != nil {
-		c.logger.Println(err)
+		opt.log(c.logger, err)
 		return nil, err
 	}
 
-	c.applyCustomHeader(hreq)
-
-	resp, err := c.http.Do(hreq)
+	resp, err := c.do(ctx, OperationPing, hreq, opt)
 	if err != nil {
-		c.logger.Println(err)
 		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, c.handleErrorResponse(resp)
-	}
-
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		c.logger.Println(err)
+		opt.log(c.logger, err)
 		return nil, err
 	}
 
 	response := new(PingResponse)
 	err = json.Unmarshal(body, response)
 	if err != nil {
-		c.logger.Println(err)
+		opt.log(c.logger, err)
 		return nil, err
 	}
 	return response, nil
 }
 
 // Account is a method to check account balance
-func (c *Client) Account(ctx context.Context) (*Account, error) {
+func (c *Client) Account(ctx context.Context, opts ...CallOptionFunc) (*Account, error) {
+	ctx, opt, cancel := callOption(ctx, opts)
+	defer cancel()
+
 	hreq, err := http.NewRequestWithContext(ctx, http.MethodGet, c.satBaseURL+ACCOUNT_PATH, nil)
 	if err != nil {
-		c.logger.Println(err)
+		opt.log(c.logger, err)
 		return nil, err
 	}
 
-	c.applyCustomHeader(hreq)
-
-	resp, err := c.http.Do(hreq)
+	resp, err := c.do(ctx, OperationAccount, hreq, opt)
 	if err != nil {
-		c.logger.Println(err)
 		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, c.handleErrorResponse(resp)
-	}
-
 	response := new(Account)
 	err = jsonapi.UnmarshalPayload(resp.Body, response)
 	if err != nil {
-		c.logger.Println(err)
+		opt.log(c.logger, err)
 		return nil, err
 	}
 
@@ -177,81 +188,90 @@ func (c *Client) Account(ctx context.Context) (*Account, error) {
 }
 
 // Inquiry is a method to get user bills based on client number and product code
-func (c *Client) Inquiry(ctx context.Context, req *InquiryRequest) (*InquiryResponse, error) {
+func (c *Client) Inquiry(ctx context.Context, req *InquiryRequest, opts ...CallOptionFunc) (*InquiryResponse, error) {
+	ctx, opt, cancel := callOption(ctx, opts)
+	defer cancel()
+
 	body := &bytes.Buffer{}
 	err := jsonapi.MarshalPayload(body, req)
 	if err != nil {
-		c.logger.Println(err)
+		opt.log(c.logger, err)
 		return nil, err
 	}
 
 	hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.satBaseURL+INQUIRY_PATH, body)
 	if err != nil {
-		c.logger.Println(err)
+		opt.log(c.logger, err)
 		return nil, err
 	}
 
-	c.applyCustomHeader(hreq)
-
-	resp, err := c.http.Do(hreq)
+	resp, err := c.do(ctx, OperationInquiry, hreq, opt)
 	if err != nil {
-		c.logger.Println(err)
 		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, c.handleErrorResponse(resp)
-	}
-
 	response := new(InquiryResponse)
 	err = jsonapi.UnmarshalPayload(resp.Body, response)
 	if err != nil {
-		c.logger.Println(err)
+		opt.log(c.logger, err)
 		return nil, err
 	}
 
+	c.inquiries.record(response, time.Now(), c.inquiryTTL)
 	return response, nil
 }
 
 // Checkout is a method to do payment an order based on client number, product code and request id.
-// Request ID should use unique identifier for each transaction
-func (c *Client) Checkout(ctx context.Context, req *OrderRequest) (*OrderDetail, error) {
+// Request ID should use unique identifier for each transaction, the empty request id is filled when WithRequestIDGenerator is set
+func (c *Client) Checkout(ctx context.Context, req *OrderRequest, opts ...CallOptionFunc) (*OrderDetail, error) {
+	ctx, opt, cancel := callOption(ctx, opts)
+	defer cancel()
+
+	err := c.prepareRequestID(req)
+	if err != nil {
+		opt.log(c.logger, err)
+		return nil, err
+	}
+
+	resp, err := c.checkout(ctx, req, opt)
+	if err != nil && c.requestIDGuard != nil {
+		c.requestIDGuard.remove(req.RequestID)
+	}
+
+	return resp, err
+}
+
+func (c *Client) checkout(ctx context.Context, req *OrderRequest, opt *CallOption) (*OrderDetail, error) {
 	body := &bytes.Buffer{}
 	err := jsonapi.MarshalPayload(body, req)
 	if err != nil {
-		c.logger.Println(err)
+		opt.log(c.logger, err)
 		return nil, err
 	}
 
 	hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.satBaseURL+CHECKOUT_PATH, body)
 	if err != nil {
-		c.logger.Println(err)
+		opt.log(c.logger, err)
 		return nil, err
 	}
 
 	sign, err := c.signature.Sign(body.Bytes())
 	if err != nil {
-		c.logger.Println(err)
+		opt.log(c.logger, err)
 		return nil, err
 	}
 
 	hreq.Header.Add(SIGNATURE_HEADER_KEY, sign)
-	c.applyCustomHeader(hreq)
 
-	resp, err := c.http.Do(hreq)
+	resp, err := c.do(ctx, OperationCheckout, hreq, opt)
 	if err != nil {
-		c.logger.Println(err)
 		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, c.handleErrorResponse(resp)
-	}
-
 	response := new(OrderDetail)
 	err = jsonapi.UnmarshalPayload(resp.Body, response)
 	if err != nil {
-		c.logger.Println(err)
+		opt.log(c.logger, err)
 		return nil, err
 	}
 
@@ -260,42 +280,40 @@ func (c *Client) Checkout(ctx context.Context, req *OrderRequest) (*OrderDetail,
 
 // CheckStatus is a method to check the final status of an order.
 // request id is must be filled
-func (c *Client) CheckStatus(ctx context.Context, requestID string) (*OrderDetail, error) {
+func (c *Client) CheckStatus(ctx context.Context, requestID string, opts ...CallOptionFunc) (*OrderDetail, error) {
+	ctx, opt, cancel := callOption(ctx, opts)
+	defer cancel()
+
 	hreq, err := http.NewRequestWithContext(ctx, http.MethodGet, c.satBaseURL+fmt.Sprintf(CHECK_STATUS_PATH, requestID), nil)
 	if err != nil {
-		c.logger.Println(err)
+		opt.log(c.logger, err)
 		return nil, err
 	}
 
-	c.applyCustomHeader(hreq)
-
-	resp, err := c.http.Do(hreq)
+	resp, err := c.do(ctx, OperationCheckStatus, hreq, opt)
 	if err != nil {
-		c.logger.Println(err)
 		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, c.handleErrorResponse(resp)
-	}
-
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		c.logger.Println(err)
+		opt.log(c.logger, err)
 		return nil, err
 	}
 
 	resp.Body = io.NopCloser(bytes.NewBuffer(body))
-	err = c.signature.Verify(string(body), resp.Header.Get(SIGNATURE_HEADER_KEY))
-	if err != nil {
-		c.logger.Println(err)
-		return nil, err
+	if !opt.skipVerification {
+		err = c.signature.Verify(string(body), resp.Header.Get(SIGNATURE_HEADER_KEY))
+		if err != nil {
+			opt.log(c.logger, err)
+			return nil, err
+		}
 	}
 
 	response := new(OrderDetail)
 	err = jsonapi.UnmarshalPayload(resp.Body, response)
 	if err != nil {
-		c.logger.Println(err)
+		opt.log(c.logger, err)
 		return nil, err
 	}
 
@@ -306,31 +324,27 @@ func (c *Client) CheckStatus(ctx context.Context, requestID string) (*OrderDetai
 // you can also specify the product code, to get only one product detail.
 // specify product code will be very beneficial to sync product status on your engine
 // it will come with low bandwidth and fast response
-func (c *Client) ListProduct(ctx context.Context, code string) ([]*Product, error) {
+func (c *Client) ListProduct(ctx context.Context, code string, opts ...CallOptionFunc) ([]*Product, error) {
+	ctx, opt, cancel := callOption(ctx, opts)
+	defer cancel()
+
 	hreq, err := http.NewRequestWithContext(ctx, http.MethodGet, c.satBaseURL+PRODUCT_LIST_PATH, nil)
 	if err != nil {
-		c.logger.Println(err)
+		opt.log(c.logger, err)
 		return nil, err
 	}
 
 	q := hreq.URL.Query()
 	q.Add("product_code", code)
 	hreq.URL.RawQuery = q.Encode()
-	c.applyCustomHeader(hreq)
-
-	resp, err := c.http.Do(hreq)
+	resp, err := c.do(ctx, OperationListProduct, hreq, opt)
 	if err != nil {
-		c.logger.Println(err)
 		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, c.handleErrorResponse(resp)
-	}
-
 	items, err := jsonapi.UnmarshalManyPayload(resp.Body, reflect.TypeOf(new(Product)))
 	if err != nil {
-		c.logger.Println(err)
+		opt.log(c.logger, err)
 		return nil, err
 	}
 
@@ -348,46 +362,52 @@ func (c *Client) GetHTTTPClient() *http.Client {
 	return c.http
 }
 
-// HandleCallback is method http.HandlerFunc to handle callback request from SAT
-// you can customize the implementation based on this interface Callback
-func (c *Client) HandleCallback(impl Callback) http.HandlerFunc {
-	return func(w http.ResponseWriter, req *http.Request) {
-		body, err := io.ReadAll(req.Body)
+// do sends the request of the operation: it waits for the rate limit, checks the circuit breaker,
+// applies the custom header and the header of the call options, and converts the non 200 response to error
+func (c *Client) do(ctx context.Context, op Operation, hreq *http.Request, opt *CallOption) (*http.Response, error) {
+	if c.rateLimiter != nil {
+		err := c.rateLimiter.Wait(ctx, op)
 		if err != nil {
-			c.logger.Println(err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
+			opt.log(c.logger, err)
+			return nil, err
 		}
+	}
 
-		err = c.signature.Verify(string(body), req.Header.Get(SIGNATURE_HEADER_KEY))
+	if c.circuitBreaker != nil {
+		err := c.circuitBreaker.allow(op)
 		if err != nil {
-			c.logger.Println(err)
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte(INVALID_SIGNATURE))
-			return
+			opt.log(c.logger, err)
+			return nil, err
 		}
+	}
 
-		request := new(OrderDetail)
-		err = jsonapi.UnmarshalPayload(bytes.NewReader(body), request)
-		if err != nil {
-			c.logger.Println(err)
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte(INVALID_PAYLOAD))
-			return
-		}
+	resp, err := c.send(op, hreq, opt)
+	if c.circuitBreaker != nil {
+		c.circuitBreaker.record(op, err)
+	}
 
-		err = impl.Do(req.Context(), request)
-		if err != nil {
-			c.logger.Println(err)
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte(err.Error()))
-			return
-		}
+	return resp, err
+}
+
+func (c *Client) send(op Operation, hreq *http.Request, opt *CallOption) (*http.Response, error) {
+	c.applyCustomHeader(hreq)
+	opt.apply(hreq)
 
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(SUCCESS_OK))
-		return
+	resp, err := c.http.Do(hreq)
+	if err != nil {
+		opt.log(c.logger, err)
+		return nil, err
+	}
+
+	if c.rateLimiter != nil {
+		c.rateLimiter.observe(op, resp)
 	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.handleErrorResponse(resp, opt)
+	}
+
+	return resp, nil
 }
 
 func (c *Client) applyCustomHeader(hreq *http.Request) {
@@ -395,7 +415,8 @@ func (c *Client) applyCustomHeader(hreq *http.Request) {
 	hreq.Header.Add("X-Sat-Sdk-Version", SAT_SDK_VERSION)
 }
 
-func (c *Client) handleErrorResponse(resp *http.Response) error {
+// handleErrorResponse converts the non 200 response to error, the failure is logged with the correlation id of the call
+func (c *Client) handleErrorResponse(resp *http.Response, opt *CallOption) error {
 	ct := resp.Header.Get("Content-Type")
 	if !strings.Contains(ct, "application/json") {
 		return &InternalError{resp: resp}
@@ -403,14 +424,14 @@ func (c *Client) handleErrorResponse(resp *http.Response) error {
 
 	b, err := io.ReadAll(resp.Body)
 	if err != nil {
-		c.logger.Println(err)
+		opt.log(c.logger, err)
 		return err
 	}
 
 	var errorResponse *ErrorResponse
 	err = json.Unmarshal(b, &errorResponse)
 	if err != nil {
-		c.logger.Println(err)
+		opt.log(c.logger, err)
 		return err
 	}
 
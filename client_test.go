@@ -894,6 +894,8 @@ func TestNewClient(t *testing.T) {
 				satBaseURL:     PLAYGROUND_SAT_BASE_URL,
 				accessTokenURL: ACCESS_TOKEN_URL,
 				isDebug:        true,
+				inquiryTTL:     DEFAULT_INQUIRY_TTL,
+				inquiries:      &inquiryClock{},
 				signature: signature.Init(signature.Options{
 					PrivateKeyString: "priv key",
 					PublicKeyString:  "pub key",
@@ -1063,10 +1065,25 @@ func TestClient_handleErrorResponse(t *testing.T) {
 				isDebug:        tt.fields.isDebug,
 			}
 
-			err := c.handleErrorResponse(tt.args.resp)
+			err := c.handleErrorResponse(tt.args.resp, &CallOption{})
 			if !reflect.DeepEqual(err, tt.wantErr) {
 				t.Errorf("handleErrorResponse() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
+
+func TestClient_handleErrorResponseCorrelationID(t *testing.T) {
+	var buf bytes.Buffer
+	c := &Client{logger: log.New(&buf, "", 0)}
+	resp := &http.Response{
+		StatusCode: http.StatusBadRequest,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader("{")),
+	}
+
+	err := c.handleErrorResponse(resp, &CallOption{correlationID: "gateway-1"})
+	if err == nil || !strings.HasPrefix(buf.String(), "gateway-1 ") {
+		t.Errorf("handleErrorResponse() logged = %q, error = %v, want the log prefixed with the correlation id", buf.String(), err)
+	}
+}
@@ -0,0 +1,144 @@
+package clientnumber
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	sat "github.com/tokopedia/golang-sat"
+)
+
+const (
+	// CategoryMobile is the category of mobile top-up and data package
+	CategoryMobile = "mobile"
+	// CategoryPLN is the category of PLN prepaid token and postpaid bill
+	CategoryPLN = "pln"
+	// CategoryBPJS is the category of BPJS Kesehatan
+	CategoryBPJS = "bpjs"
+)
+
+var (
+	// ErrInvalidNumber is returned when the client number format is invalid for the category
+	ErrInvalidNumber = errors.New("client number is invalid")
+	// ErrUnknownOperator is returned when the operator of the client number can't be detected
+	ErrUnknownOperator = errors.New("client number operator is unknown")
+	// ErrUnknownCategory is returned when no validator is registered for the category
+	ErrUnknownCategory = errors.New("client number category is unknown")
+)
+
+// Result contains the normalized client number and the detected operator,
+// OperatorName is the same with sat.Product OperatorName
+type Result struct {
+	Number       string
+	Category     string
+	OperatorName string
+	// Prefix is the number prefix identifying the operator, it is empty when only the length is checked
+	Prefix string
+}
+
+// Validator validates and normalizes the client number of a category
+type Validator interface {
+	Validate(number string) (*Result, error)
+}
+
+// ValidatorFunc is an adapter to use a function as Validator
+type ValidatorFunc func(number string) (*Result, error)
+
+// Validate calls f(number)
+func (f ValidatorFunc) Validate(number string) (*Result, error) {
+	return f(number)
+}
+
+// Registry contains the validator of every category
+type Registry struct {
+	categories []string
+	validators map[string]Validator
+}
+
+// NewRegistry will return a new registry with mobile, PLN and BPJS validators
+func NewRegistry() *Registry {
+	r := &Registry{validators: map[string]Validator{}}
+	r.Register(CategoryMobile, Mobile(DefaultMobilePrefixes()))
+	r.Register(CategoryPLN, PLN())
+	r.Register(CategoryBPJS, BPJS())
+
+	return r
+}
+
+// Register set the validator of the category, the existing validator is replaced
+func (r *Registry) Register(category string, validator Validator) {
+	if _, ok := r.validators[category]; !ok {
+		r.categories = append(r.categories, category)
+	}
+
+	r.validators[category] = validator
+}
+
+// Validate will return the normalized client number using the validator of the category
+func (r *Registry) Validate(category, number string) (*Result, error) {
+	validator, ok := r.validators[category]
+	if !ok {
+		return nil, ErrUnknownCategory
+	}
+
+	return validator.Validate(number)
+}
+
+// Detect will return the result of every category accepting the client number, the most specific first.
+// The result with the longer prefix is more specific, the same specificity is kept in registration order
+func (r *Registry) Detect(number string) []*Result {
+	var results []*Result
+	for _, category := range r.categories {
+		result, err := r.validators[category].Validate(number)
+		if err != nil {
+			continue
+		}
+
+		results = append(results, result)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return len(results[i].Prefix) > len(results[j].Prefix)
+	})
+
+	return results
+}
+
+// Suggest will return the products of the operator detected from the client number,
+// example the Telkomsel products for 0812xxxx. Only the most specific results are used,
+// so the mobile number is not suggested the PLN products even if it has the length of PLN number
+func (r *Registry) Suggest(products []*sat.Product, number string, opts ...sat.QueryOptionFunc) []*sat.Product {
+	var operators []string
+	results := r.Detect(number)
+	for _, result := range results {
+		if len(result.Prefix) < len(results[0].Prefix) {
+			break
+		}
+
+		if result.OperatorName != "" {
+			operators = append(operators, result.OperatorName)
+		}
+	}
+
+	if len(operators) == 0 {
+		return nil
+	}
+
+	return sat.QueryProducts(products, append([]sat.QueryOptionFunc{sat.WithOperator(operators...)}, opts...)...)
+}
+
+// digits removes the common separators, it returns false when other non digit character is found
+func digits(number string) (string, bool) {
+	var b strings.Builder
+	for _, r := range number {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return "", false
+		}
+	}
+
+	return b.String(), b.Len() > 0
+}
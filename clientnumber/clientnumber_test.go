@@ -0,0 +1,88 @@
+package clientnumber
+
+import (
+	"errors"
+	"testing"
+
+	sat "github.com/tokopedia/golang-sat"
+)
+
+func TestRegistry_Validate(t *testing.T) {
+	r := NewRegistry()
+	tests := []struct {
+		name     string
+		category string
+		number   string
+		want     *Result
+		wantErr  error
+	}{
+		{"mobile local", CategoryMobile, "0812-3456-7890", &Result{"081234567890", CategoryMobile, "Telkomsel", "0812"}, nil},
+		{"mobile +62", CategoryMobile, "+62 857 1234 5678", &Result{"085712345678", CategoryMobile, "Indosat", "0857"}, nil},
+		{"mobile 62", CategoryMobile, "6287812345678", &Result{"087812345678", CategoryMobile, "XL", "0878"}, nil},
+		{"mobile unknown prefix", CategoryMobile, "08001234567", nil, ErrUnknownOperator},
+		{"mobile too short", CategoryMobile, "08123", nil, ErrInvalidNumber},
+		{"mobile letters", CategoryMobile, "0812abc", nil, ErrInvalidNumber},
+		{"pln meter", CategoryPLN, "141 2345 6789", &Result{"14123456789", CategoryPLN, "PLN", ""}, nil},
+		{"pln invalid", CategoryPLN, "12345", nil, ErrInvalidNumber},
+		{"bpjs card", CategoryBPJS, "0001234567890", &Result{"0001234567890", CategoryBPJS, "BPJS", ""}, nil},
+		{"bpjs va", CategoryBPJS, "8888801234567890", &Result{"8888801234567890", CategoryBPJS, "BPJS", "88888"}, nil},
+		{"bpjs invalid", CategoryBPJS, "1234567890123456", nil, ErrInvalidNumber},
+		{"unknown category", "pdam", "123", nil, ErrUnknownCategory},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.Validate(tt.category, tt.number)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.want != nil && *got != *tt.want {
+				t.Errorf("Validate() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_Suggest(t *testing.T) {
+	r := NewRegistry()
+	r.Register("pdam", Digits("pdam", "PDAM Surabaya", 8, 10))
+
+	products := []*sat.Product{
+		{Code: "telkomsel-10k", OperatorName: "Telkomsel", SalesPrice: 10200, Status: sat.ProductStatusActive},
+		{Code: "telkomsel-5k", OperatorName: "Telkomsel", SalesPrice: 5200, Status: sat.ProductStatusActive},
+		{Code: "indosat-10k", OperatorName: "Indosat", SalesPrice: 10100, Status: sat.ProductStatusActive},
+		{Code: "pdam-surabaya", OperatorName: "PDAM Surabaya", Status: sat.ProductStatusActive},
+		{Code: "pln-prepaid-token-50k", OperatorName: "PLN", SalesPrice: 50500, Status: sat.ProductStatusActive},
+		{Code: "bpjs-kesehatan", OperatorName: "BPJS", Status: sat.ProductStatusActive},
+	}
+
+	got := r.Suggest(products, "+6281234567890", sat.WithSort(sat.SortByPrice, false))
+	if len(got) != 2 || got[0].Code != "telkomsel-5k" || got[1].Code != "telkomsel-10k" {
+		t.Errorf("Suggest() got = %v", got)
+	}
+
+	// the 13 digits mobile number has the length of BPJS card number too
+	got = r.Suggest(products, "0812-3456-78901")
+	if len(got) != 2 || got[0].OperatorName != "Telkomsel" || got[1].OperatorName != "Telkomsel" {
+		t.Errorf("Suggest() got = %v, want only Telkomsel products", got)
+	}
+
+	if detected := r.Detect("081234567890"); len(detected) != 2 || detected[0].Category != CategoryMobile || detected[1].Category != CategoryPLN {
+		t.Errorf("Detect() got = %v, want mobile before PLN", detected)
+	}
+
+	got = r.Suggest(products, "14123456789")
+	if len(got) != 1 || got[0].Code != "pln-prepaid-token-50k" {
+		t.Errorf("Suggest() got = %v, want PLN products", got)
+	}
+
+	got = r.Suggest(products, "12345678")
+	if len(got) != 1 || got[0].Code != "pdam-surabaya" {
+		t.Errorf("Suggest() got = %v", got)
+	}
+
+	if got := r.Suggest(products, "abc"); got != nil {
+		t.Errorf("Suggest() got = %v, want nil", got)
+	}
+}
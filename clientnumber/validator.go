@@ -0,0 +1,92 @@
+package clientnumber
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultMobilePrefixes will return the prefix to operator name of the major Indonesian carriers
+func DefaultMobilePrefixes() map[string]string {
+	prefixes := map[string]string{}
+	operators := map[string][]string{
+		"Telkomsel": {"0811", "0812", "0813", "0821", "0822", "0823", "0851", "0852", "0853"},
+		"Indosat":   {"0814", "0815", "0816", "0855", "0856", "0857", "0858"},
+		"XL":        {"0817", "0818", "0819", "0859", "0877", "0878"},
+		"Axis":      {"0831", "0832", "0833", "0838"},
+		"Tri":       {"0895", "0896", "0897", "0898", "0899"},
+		"Smartfren": {"0881", "0882", "0883", "0884", "0885", "0886", "0887", "0888", "0889"},
+	}
+
+	for operator, list := range operators {
+		for _, prefix := range list {
+			prefixes[prefix] = operator
+		}
+	}
+
+	return prefixes
+}
+
+// Mobile will return the validator of Indonesian mobile number. The number is normalized
+// from +62, 62 or 0 format into 0 format, and the operator is detected using the prefixes
+func Mobile(prefixes map[string]string) Validator {
+	return ValidatorFunc(func(number string) (*Result, error) {
+		n, ok := digits(strings.TrimPrefix(strings.TrimSpace(number), "+"))
+		if !ok {
+			return nil, fmt.Errorf("%w: %s must be digits", ErrInvalidNumber, number)
+		}
+
+		switch {
+		case strings.HasPrefix(n, "62"):
+			n = "0" + n[2:]
+		case strings.HasPrefix(n, "8"):
+			n = "0" + n
+		}
+
+		if !strings.HasPrefix(n, "08") || len(n) < 10 || len(n) > 13 {
+			return nil, fmt.Errorf("%w: %s is not a mobile number", ErrInvalidNumber, number)
+		}
+
+		operator, ok := prefixes[n[:4]]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownOperator, number)
+		}
+
+		return &Result{Number: n, Category: CategoryMobile, OperatorName: operator, Prefix: n[:4]}, nil
+	})
+}
+
+// PLN will return the validator of PLN meter number (11 digits) or customer ID (12 digits)
+func PLN() Validator {
+	return Digits(CategoryPLN, "PLN", 11, 12)
+}
+
+// BPJS will return the validator of BPJS Kesehatan card number (13 digits)
+// or virtual account number (16 digits started with 88888)
+func BPJS() Validator {
+	return ValidatorFunc(func(number string) (*Result, error) {
+		n, ok := digits(number)
+		if !ok || (len(n) != 13 && !(len(n) == 16 && strings.HasPrefix(n, "88888"))) {
+			return nil, fmt.Errorf("%w: %s is not a BPJS number", ErrInvalidNumber, number)
+		}
+
+		result := &Result{Number: n, Category: CategoryBPJS, OperatorName: "BPJS"}
+		if len(n) == 16 {
+			result.Prefix = n[:5]
+		}
+
+		return result, nil
+	})
+}
+
+// Digits will return the validator accepting only digits within the length,
+// example for PDAM customer number which the format depends on the region
+func Digits(category, operatorName string, min, max int) Validator {
+	return ValidatorFunc(func(number string) (*Result, error) {
+		n, ok := digits(number)
+		if !ok || len(n) < min || len(n) > max {
+			return nil, fmt.Errorf("%w: %s must be %d to %d digits", ErrInvalidNumber, number, min, max)
+		}
+
+		return &Result{Number: n, Category: category, OperatorName: operatorName}, nil
+	})
+}
@@ -0,0 +1,132 @@
+package sat
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Coalesce is a Middleware sharing one in-flight call and its result between the concurrent identical
+// ListProduct, Account, CheckStatus and Inquiry calls with the same call options. The shared call keeps running while any caller still waits,
+// every caller returns when its own ctx is done. The result is shared by the callers, don't modify it
+func Coalesce() Middleware {
+	return func(next API) API {
+		g := &flightGroup{calls: map[string]*flight{}}
+
+		return &Decorator{
+			Next: next,
+			AccountFunc: func(ctx context.Context) (*Account, error) {
+				v, err := g.do(ctx, string(OperationAccount), func(ctx context.Context) (interface{}, error) {
+					return next.Account(ctx)
+				})
+				resp, _ := v.(*Account)
+				return resp, err
+			},
+			InquiryFunc: func(ctx context.Context, req *InquiryRequest) (*InquiryResponse, error) {
+				v, err := g.do(ctx, inquiryFlightKey(req), func(ctx context.Context) (interface{}, error) {
+					return next.Inquiry(ctx, req)
+				})
+				resp, _ := v.(*InquiryResponse)
+				return resp, err
+			},
+			CheckStatusFunc: func(ctx context.Context, requestID string) (*OrderDetail, error) {
+				v, err := g.do(ctx, string(OperationCheckStatus)+"|"+requestID, func(ctx context.Context) (interface{}, error) {
+					return next.CheckStatus(ctx, requestID)
+				})
+				resp, _ := v.(*OrderDetail)
+				return resp, err
+			},
+			ListProductFunc: func(ctx context.Context, code string) ([]*Product, error) {
+				v, err := g.do(ctx, string(OperationListProduct)+"|"+code, func(ctx context.Context) (interface{}, error) {
+					return next.ListProduct(ctx, code)
+				})
+				resp, _ := v.([]*Product)
+				return resp, err
+			},
+		}
+	}
+}
+
+// inquiryFlightKey is the key of the identical inquiry, every field of the request is part of the key
+func inquiryFlightKey(req *InquiryRequest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s|%s|%s|%s|%d|%s", OperationInquiry, req.ID, req.ProductCode, req.ClientNumber, req.Amount, req.DownlineID)
+	for _, field := range req.Fields {
+		fmt.Fprintf(&b, "|%s=%s", field.Name, field.Value)
+	}
+
+	return b.String()
+}
+
+type flight struct {
+	done    chan struct{}
+	val     interface{}
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// flightGroup runs one call for every key at a time
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flight
+}
+
+// do calls fn once for the concurrent callers of the same key and call options. fn gets a ctx carrying the values of the first caller,
+// it is cancelled only when every caller has returned
+func (g *flightGroup) do(ctx context.Context, key string, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	key += "|" + newCallOption(ctx, nil).key()
+
+	g.mu.Lock()
+	f, ok := g.calls[key]
+	if !ok {
+		fctx, cancel := context.WithCancel(detachedContext{ctx})
+		f = &flight{done: make(chan struct{}), cancel: cancel}
+		g.calls[key] = f
+
+		go func() {
+			f.val, f.err = fn(fctx)
+			cancel()
+
+			g.mu.Lock()
+			if g.calls[key] == f {
+				delete(g.calls, key)
+			}
+			g.mu.Unlock()
+
+			close(f.done)
+		}()
+	}
+	f.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.val, f.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		f.waiters--
+		if f.waiters == 0 {
+			f.cancel()
+			// the next caller starts a new call instead of joining the cancelled one
+			if g.calls[key] == f {
+				delete(g.calls, key)
+			}
+		}
+		g.mu.Unlock()
+
+		return nil, ctx.Err()
+	}
+}
+
+// detachedContext keeps the values of the parent without its deadline and cancellation
+type detachedContext struct {
+	parent context.Context
+}
+
+func (d detachedContext) Deadline() (time.Time, bool)       { return time.Time{}, false }
+func (d detachedContext) Done() <-chan struct{}             { return nil }
+func (d detachedContext) Err() error                        { return nil }
+func (d detachedContext) Value(key interface{}) interface{} { return d.parent.Value(key) }
@@ -0,0 +1,126 @@
+package sat
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCoalesce(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	next := &Decorator{
+		ListProductFunc: func(ctx context.Context, code string) ([]*Product, error) {
+			atomic.AddInt32(&calls, 1)
+			<-release
+			return []*Product{{Code: code}}, nil
+		},
+	}
+	api := Chain(next, Coalesce())
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancelledErr := make(chan error, 1)
+	go func() {
+		_, err := api.ListProduct(cancelled, "telkomsel-10k")
+		cancelledErr <- err
+	}()
+
+	var wg sync.WaitGroup
+	results := make([][]*Product, 5)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = api.ListProduct(context.Background(), "telkomsel-10k")
+		}(i)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	if err := <-cancelledErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("ListProduct() cancelled caller error = %v, want %v", err, context.Canceled)
+	}
+
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("ListProduct() called %d times, want 1", calls)
+	}
+
+	for i, result := range results {
+		if len(result) != 1 || result[0].Code != "telkomsel-10k" {
+			t.Errorf("ListProduct() caller %d got = %v", i, result)
+		}
+	}
+
+	// the call is not shared after it is returned
+	api.ListProduct(context.Background(), "telkomsel-10k")
+	if calls != 2 {
+		t.Errorf("ListProduct() called %d times after the first call, want 2", calls)
+	}
+}
+
+func TestCoalesce_AllCancelled(t *testing.T) {
+	stopped := make(chan error, 1)
+	next := &Decorator{
+		CheckStatusFunc: func(ctx context.Context, requestID string) (*OrderDetail, error) {
+			<-ctx.Done()
+			stopped <- ctx.Err()
+			return nil, ctx.Err()
+		},
+	}
+	api := Chain(next, Coalesce())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := api.CheckStatus(ctx, "order-1")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("CheckStatus() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("CheckStatus() shared call is not cancelled after every caller returned")
+	}
+}
+
+func TestCoalesce_CallOptions(t *testing.T) {
+	var mu sync.Mutex
+	calls := map[string]int{}
+	release := make(chan struct{})
+	next := &Decorator{
+		CheckStatusFunc: func(ctx context.Context, requestID string) (*OrderDetail, error) {
+			correlationID := newCallOption(ctx, nil).correlationID
+			mu.Lock()
+			calls[correlationID]++
+			mu.Unlock()
+
+			<-release
+			return &OrderDetail{RequestID: requestID}, nil
+		},
+	}
+	api := Chain(next, Coalesce())
+
+	var wg sync.WaitGroup
+	for _, correlationID := range []string{"gateway-1", "gateway-2", "gateway-1"} {
+		wg.Add(1)
+		go func(correlationID string) {
+			defer wg.Done()
+			api.CheckStatus(context.Background(), "order-1", WithCorrelationID(correlationID))
+		}(correlationID)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls["gateway-1"] != 1 || calls["gateway-2"] != 1 {
+		t.Errorf("CheckStatus() calls by correlation id got = %v, want one call each", calls)
+	}
+}
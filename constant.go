@@ -1,5 +1,7 @@
 package sat
 
+import "time"
+
 const (
 	// ACCESS_TOKEN_URL is constant full url for oauth
 	ACCESS_TOKEN_URL = "https://accounts.tokopedia.com/token"
@@ -23,6 +25,23 @@ const (
 
 	// SIGNATURE_HEADER_KEY is the key name used as header http of digital signature
 	SIGNATURE_HEADER_KEY = "signature"
+	// CORRELATION_ID_HEADER_KEY is the header name of the correlation id set by WithCorrelationID
+	CORRELATION_ID_HEADER_KEY = "X-Request-ID"
+	// IDEMPOTENCY_KEY_HEADER_KEY is the header name of the idempotency key set by WithIdempotencyKey
+	IDEMPOTENCY_KEY_HEADER_KEY = "Idempotency-Key"
+	// RETRY_AFTER_HEADER_KEY is the header name of how long to wait after 429 response
+	RETRY_AFTER_HEADER_KEY = "Retry-After"
+	// RATE_LIMIT_REMAINING_HEADER_KEY is the header name of the remaining calls in the current window
+	RATE_LIMIT_REMAINING_HEADER_KEY = "X-RateLimit-Remaining"
+	// RATE_LIMIT_RESET_HEADER_KEY is the header name of the seconds until the current window is reset
+	RATE_LIMIT_RESET_HEADER_KEY = "X-RateLimit-Reset"
+	// REF_ID_FIELD is the field name carrying the inquiry reference id to the order
+	REF_ID_FIELD = "ref_id"
+	// DEFAULT_REQUEST_ID_MAX_LENGTH is the max length of request id
+	DEFAULT_REQUEST_ID_MAX_LENGTH = 64
+	// DEFAULT_INQUIRY_TTL is how long the inquiry result can be paid by default
+	DEFAULT_INQUIRY_TTL = 15 * time.Minute
+
 	// SAT_SDK_VERSION is current sdk version
 	SAT_SDK_VERSION = "golang-sat@v1.0.0"
 )
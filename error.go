@@ -1,8 +1,10 @@
 package sat
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/google/jsonapi"
 )
@@ -14,6 +16,29 @@ const (
 	INVALID_SIGNATURE = "INVALID_SIGNATURE"
 	// INVALID_PAYLOAD contains invalid payload message
 	INVALID_PAYLOAD = "INVALID_PAYLOAD"
+	// CALLBACK_RETRY contains callback should be sent again later message
+	CALLBACK_RETRY = "RETRY_LATER"
+	// CALLBACK_REJECTED contains callback is rejected permanently message
+	CALLBACK_REJECTED = "REJECTED"
+	// CROSS_VERIFICATION_FAILED contains failed to re-fetch the order message
+	CROSS_VERIFICATION_FAILED = "CROSS_VERIFICATION_FAILED"
+
+	// PRODUCT_NOT_FOUND contains product is not available message
+	PRODUCT_NOT_FOUND = "product is not found"
+	// INSUFFICIENT_BALANCE contains balance is not enough for the order message
+	INSUFFICIENT_BALANCE = "INSUFFICIENT_BALANCE"
+	// INQUIRY_EXPIRED contains inquiry is too old to be paid message
+	INQUIRY_EXPIRED = "inquiry is expired"
+	// DUPLICATE_REQUEST_ID contains request id is already submitted message
+	DUPLICATE_REQUEST_ID = "request id is already submitted"
+	// BATCH_STOPPED contains order is not submitted because the batch is stopped message
+	BATCH_STOPPED = "batch is stopped by balance error"
+	// RATE_LIMITED contains operation has no rate limit token available message
+	RATE_LIMITED = "rate limit is exceeded"
+	// CIRCUIT_OPEN contains call is rejected by the open circuit message
+	CIRCUIT_OPEN = "circuit is open"
+	// VALIDATION_FAILED contains request is rejected before sent to SAT message
+	VALIDATION_FAILED = "VALIDATION_FAILED"
 
 	// EMPTY_CLIENT_ID contains an empty client id error message
 	EMPTY_CLIENT_ID = "client id can't be empty"
@@ -75,6 +100,30 @@ func (e *ErrorResponse) Detail() string {
 	return e.Errors[0].Detail
 }
 
+// errorStatus will return the status of the error response, it is empty when err is not answered by SAT
+func errorStatus(err error) string {
+	var errR *ErrorResponse
+	if !errors.As(err, &errR) {
+		return ""
+	}
+
+	return errR.Status()
+}
+
+// isRejected will return true when SAT answers with 4xx except 429, the order is surely not created
+func isRejected(err error) bool {
+	status := errorStatus(err)
+	return strings.HasPrefix(status, "4") && status != "429"
+}
+
+// isNotSent will return true when err is raised before the request is sent, the order is surely not created.
+// The timeout and the cancelled context are not, the request may be sent already
+func isNotSent(err error) bool {
+	var verr *ValidationError
+	var balanceErr *InsufficientBalanceError
+	return errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrRateLimited) || errors.As(err, &verr) || errors.As(err, &balanceErr)
+}
+
 // APIInternalError for internal error produces by non-SAT server
 type APIInternalError interface {
 	Error() string
@@ -95,3 +144,44 @@ func (i *InternalError) Error() string {
 func (i *InternalError) Response() *http.Response {
 	return i.resp
 }
+
+// InvalidSignatureError wrapper error when the callback signature can't be verified
+type InvalidSignatureError struct {
+	err error
+}
+
+// Error will return invalid signature message and the cause
+func (i *InvalidSignatureError) Error() string {
+	return fmt.Sprintf("%s - %s", INVALID_SIGNATURE, i.err)
+}
+
+// Unwrap will return the cause of the error
+func (i *InvalidSignatureError) Unwrap() error {
+	return i.err
+}
+
+// InvalidPayloadError wrapper error when the callback payload can't be decoded
+type InvalidPayloadError struct {
+	err error
+}
+
+// Error will return invalid payload message and the cause
+func (i *InvalidPayloadError) Error() string {
+	return fmt.Sprintf("%s - %s", INVALID_PAYLOAD, i.err)
+}
+
+// Unwrap will return the cause of the error
+func (i *InvalidPayloadError) Unwrap() error {
+	return i.err
+}
+
+// InsufficientBalanceError is returned when the order price exceeds the known balance, the order is not sent to SAT
+type InsufficientBalanceError struct {
+	Balance int64
+	Price   int64
+}
+
+// Error will return insufficient balance message with the balance and the price
+func (i *InsufficientBalanceError) Error() string {
+	return fmt.Sprintf("%s - balance %d, price %d", INSUFFICIENT_BALANCE, i.Balance, i.Price)
+}
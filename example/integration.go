@@ -4,26 +4,25 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"math/rand"
 	"net/http"
-	"strconv"
 	"time"
 
 	sat "github.com/tokopedia/golang-sat"
 )
 
 type integrationExample struct {
-	client *sat.Client
+	client  *sat.Client
+	catalog *sat.ProductCatalog
 }
 
 func (i *integrationExample) Handle(writer http.ResponseWriter, request *http.Request) {
 	ctx := request.Context()
 
 	// checking product before doing inquiry or checkout is optional.
-	// this implementation is only for sample to trigger a get list of product via SDK.
-	// ListProduct will be beneficial for sync product status.
-	// make your product status always up to date
-	resProductList, err := i.client.ListProduct(ctx, "pln-prepaid-token-50k-sat")
+	// this implementation is only for sample to get a product via product catalog.
+	// product catalog serves the product from memory and keeps it in sync in background,
+	// so it doesn't hit ListProduct before each purchase
+	resProduct, err := i.catalog.Get(ctx, "pln-prepaid-token-50k-sat")
 
 	var errR sat.APIResponseError
 	ok := errors.As(err, &errR)
@@ -35,7 +34,7 @@ func (i *integrationExample) Handle(writer http.ResponseWriter, request *http.Re
 		fmt.Println(err)
 	}
 
-	fmt.Println("[PRODUCT LIST] response: ", resProductList)
+	fmt.Println("[PRODUCT LIST] response: ", resProduct)
 
 	resInq, err := i.client.Inquiry(ctx, &sat.InquiryRequest{
 		ProductCode:  "pln-prepaid-token-50k-sat",
@@ -63,13 +62,13 @@ func (i *integrationExample) Handle(writer http.ResponseWriter, request *http.Re
 
 	fmt.Println("[INQUIRY] response: ", resInq)
 
-	reqID := strconv.Itoa(rand.Int())
-
-	resOrder, err := i.client.Checkout(ctx, &sat.OrderRequest{
+	// the empty request id is filled by the request id generator
+	order := &sat.OrderRequest{
 		ProductCode:  "pln-prepaid-token-100k",
 		ClientNumber: "102111106111",
-		RequestID:    reqID,
-	})
+	}
+	resOrder, err := i.client.Checkout(ctx, order)
+	reqID := order.RequestID
 
 	ok = errors.As(err, &errR)
 	if ok {
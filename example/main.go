@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"net/http"
@@ -23,12 +24,20 @@ func main() {
 		sat.WithPaddingType(signature.PaddingTypePSS),
 		sat.WithIsDebug(true),
 		sat.WithHTTPClient(c),
+		sat.WithRequestIDGenerator(sat.NewULIDGenerator()),
 	)
 	if err != nil {
 		panic(err)
 	}
 
-	ie := integrationExample{client: cln}
+	catalog := sat.NewProductCatalog(cln, sat.WithRefreshInterval(5*time.Minute))
+	err = catalog.Start(context.Background())
+	if err != nil {
+		fmt.Println(err)
+	}
+	defer catalog.Stop()
+
+	ie := integrationExample{client: cln, catalog: catalog}
 	clbe := &callbackExample{}
 
 	http.HandleFunc("/test", ie.Handle)
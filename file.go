@@ -0,0 +1,31 @@
+package sat
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// writeFileAtomic writes the data into a temporary file and renames it,
+// so the file is never left half written when the process is crashed
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+
+	if errC := tmp.Close(); err == nil {
+		err = errC
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
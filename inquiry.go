@@ -43,7 +43,9 @@ type Field struct {
 
 // UnmarshalJSON override unmarshal jsonapi
 func (s *Field) UnmarshalJSON(data []byte) error {
-	err := json.Unmarshal(data, s)
+	// field has no UnmarshalJSON method, unmarshal to Field itself would call this method again
+	type field Field
+	err := json.Unmarshal(data, (*field)(s))
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,265 @@
+package sat
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// InquiryCacheStore keeps the cached inquiry responses, implement it using a shared storage
+// example redis, to share the cache between instances
+type InquiryCacheStore interface {
+	// Get will return false when the key is not found or expired
+	Get(ctx context.Context, key string) (*InquiryResponse, bool, error)
+	Set(ctx context.Context, key string, resp *InquiryResponse, ttl time.Duration) error
+	// DeletePrefix removes every key starting with the prefix
+	DeletePrefix(ctx context.Context, prefix string) error
+}
+
+type memoryInquiryEntry struct {
+	resp      InquiryResponse
+	expiredAt time.Time
+}
+
+// MemoryInquiryCacheStore keeps the cached inquiry responses in memory
+type MemoryInquiryCacheStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryInquiryEntry
+}
+
+// NewMemoryInquiryCacheStore will return a new memory inquiry cache store
+func NewMemoryInquiryCacheStore() *MemoryInquiryCacheStore {
+	return &MemoryInquiryCacheStore{entries: map[string]memoryInquiryEntry{}}
+}
+
+// Get will return a copy of the cached inquiry response
+func (s *MemoryInquiryCacheStore) Get(ctx context.Context, key string) (*InquiryResponse, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok || time.Now().After(entry.expiredAt) {
+		return nil, false, nil
+	}
+
+	return copyInquiryResponse(&entry.resp), true, nil
+}
+
+// Set keeps a copy of the inquiry response until the ttl, the expired entries are removed
+func (s *MemoryInquiryCacheStore) Set(ctx context.Context, key string, resp *InquiryResponse, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, entry := range s.entries {
+		if now.After(entry.expiredAt) {
+			delete(s.entries, k)
+		}
+	}
+
+	s.entries[key] = memoryInquiryEntry{resp: *copyInquiryResponse(resp), expiredAt: now.Add(ttl)}
+	return nil
+}
+
+// DeletePrefix removes every cached inquiry response of the key starting with the prefix
+func (s *MemoryInquiryCacheStore) DeletePrefix(ctx context.Context, prefix string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key := range s.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(s.entries, key)
+		}
+	}
+
+	return nil
+}
+
+// copyInquiryResponse will return a copy of the inquiry response not sharing the fields
+func copyInquiryResponse(resp *InquiryResponse) *InquiryResponse {
+	c := *resp
+	c.Fields = append(Fields(nil), resp.Fields...)
+	c.InquiryResult = append(Fields(nil), resp.InquiryResult...)
+	return &c
+}
+
+// InquiryCacheOption contains field you can configure on the inquiry cache
+type InquiryCacheOption struct {
+	logger      *log.Logger
+	store       InquiryCacheStore
+	ttl         time.Duration
+	categoryTTL map[string]time.Duration
+	products    ProductLookup
+}
+
+var defaultInquiryCacheOption = InquiryCacheOption{
+	logger: log.New(log.Writer(), "[sat] ", 0),
+	ttl:    time.Minute,
+}
+
+type InquiryCacheOptionFunc func(*InquiryCacheOption)
+
+// WithInquiryCacheLogger override existing logger
+func WithInquiryCacheLogger(logger *log.Logger) InquiryCacheOptionFunc {
+	return func(o *InquiryCacheOption) {
+		o.logger = logger
+	}
+}
+
+// WithInquiryCacheStore set the store of the cached inquiry responses, default is in memory
+func WithInquiryCacheStore(store InquiryCacheStore) InquiryCacheOptionFunc {
+	return func(o *InquiryCacheOption) {
+		o.store = store
+	}
+}
+
+// WithCacheTTL set how long the inquiry response is cached, used when the category has no ttl
+func WithCacheTTL(ttl time.Duration) InquiryCacheOptionFunc {
+	return func(o *InquiryCacheOption) {
+		o.ttl = ttl
+	}
+}
+
+// WithCategoryTTL set how long the inquiry response of the product category is cached, 0 disables the cache of the category.
+// The category of the product is looked up using WithCacheProducts
+func WithCategoryTTL(category string, ttl time.Duration) InquiryCacheOptionFunc {
+	return func(o *InquiryCacheOption) {
+		categoryTTL := make(map[string]time.Duration, len(o.categoryTTL)+1)
+		for k, v := range o.categoryTTL {
+			categoryTTL[k] = v
+		}
+		categoryTTL[category] = ttl
+		o.categoryTTL = categoryTTL
+	}
+}
+
+// WithCacheProducts set the product lookup used to find the category of the product, example the product catalog
+func WithCacheProducts(products ProductLookup) InquiryCacheOptionFunc {
+	return func(o *InquiryCacheOption) {
+		o.products = products
+	}
+}
+
+type forceRefreshKey struct{}
+
+// ForceRefresh will return a context making the inquiry cache call SAT and replace the cached response
+func ForceRefresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forceRefreshKey{}, true)
+}
+
+// InquiryCache serves the repeated inquiry of the same product code, client number, downline id, amount and fields from the cache.
+// Every cached response of the product code, client number and downline id is removed after the successful checkout of them
+type InquiryCache struct {
+	opt InquiryCacheOption
+}
+
+// NewInquiryCache will return a new inquiry cache
+func NewInquiryCache(opts ...InquiryCacheOptionFunc) *InquiryCache {
+	opt := defaultInquiryCacheOption
+	for _, option := range opts {
+		option(&opt)
+	}
+
+	if opt.store == nil {
+		opt.store = NewMemoryInquiryCacheStore()
+	}
+
+	return &InquiryCache{opt: opt}
+}
+
+// Middleware will return a Middleware caching every successful inquiry and invalidating it after the successful checkout.
+// The cache failure is logged and the call is passed to SAT
+func (c *InquiryCache) Middleware() Middleware {
+	return func(next API) API {
+		return &Decorator{
+			Next: next,
+			InquiryFunc: func(ctx context.Context, req *InquiryRequest) (*InquiryResponse, error) {
+				key := inquiryCacheKey(req)
+				if refresh, _ := ctx.Value(forceRefreshKey{}).(bool); !refresh {
+					resp, ok, err := c.opt.store.Get(ctx, key)
+					if err != nil {
+						c.opt.logger.Println(err)
+					}
+
+					if ok {
+						return resp, nil
+					}
+				}
+
+				resp, err := next.Inquiry(ctx, req)
+				if err != nil {
+					return nil, err
+				}
+
+				ttl := c.ttl(ctx, req.ProductCode)
+				if ttl <= 0 {
+					return resp, nil
+				}
+
+				err = c.opt.store.Set(ctx, key, resp, ttl)
+				if err != nil {
+					c.opt.logger.Println(err)
+				}
+
+				return resp, nil
+			},
+			CheckoutFunc: func(ctx context.Context, req *OrderRequest) (*OrderDetail, error) {
+				resp, err := next.Checkout(ctx, req)
+				if err != nil {
+					return nil, err
+				}
+
+				err = c.Invalidate(ctx, req.ProductCode, req.ClientNumber, req.DownlineID)
+				if err != nil {
+					c.opt.logger.Println(err)
+				}
+
+				return resp, nil
+			},
+		}
+	}
+}
+
+// Invalidate removes every cached inquiry response of the product code, client number and downline id,
+// example after the bill is paid outside this client
+func (c *InquiryCache) Invalidate(ctx context.Context, productCode, clientNumber, downlineID string) error {
+	return c.opt.store.DeletePrefix(ctx, inquiryCachePrefix(productCode, clientNumber, downlineID))
+}
+
+// ttl will return the ttl of the product category, or the default ttl when the category is unknown
+func (c *InquiryCache) ttl(ctx context.Context, productCode string) time.Duration {
+	if c.opt.products == nil || len(c.opt.categoryTTL) == 0 {
+		return c.opt.ttl
+	}
+
+	product, err := c.opt.products.Get(ctx, productCode)
+	if err != nil {
+		c.opt.logger.Println(err)
+		return c.opt.ttl
+	}
+
+	ttl, ok := c.opt.categoryTTL[product.CategoryName]
+	if !ok {
+		return c.opt.ttl
+	}
+
+	return ttl
+}
+
+// inquiryCacheKey is the key of the identical inquiry, it starts with the prefix of the product code, client number and downline id
+func inquiryCacheKey(req *InquiryRequest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s%d", inquiryCachePrefix(req.ProductCode, req.ClientNumber, req.DownlineID), req.Amount)
+	for _, field := range req.Fields {
+		fmt.Fprintf(&b, "|%s=%s", field.Name, field.Value)
+	}
+
+	return b.String()
+}
+
+func inquiryCachePrefix(productCode, clientNumber, downlineID string) string {
+	return productCode + "|" + clientNumber + "|" + downlineID + "|"
+}
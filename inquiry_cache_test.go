@@ -0,0 +1,115 @@
+package sat
+
+import (
+	"context"
+	"io"
+	"log"
+	"testing"
+	"time"
+)
+
+func TestInquiryCache(t *testing.T) {
+	ctx := context.Background()
+
+	calls := map[string]int{}
+	next := &Decorator{
+		InquiryFunc: func(ctx context.Context, req *InquiryRequest) (*InquiryResponse, error) {
+			calls[req.ProductCode+"|"+req.ClientNumber]++
+			return &InquiryResponse{ProductCode: req.ProductCode, ClientNumber: req.ClientNumber, SalesPrice: int64(50000 + calls[req.ProductCode+"|"+req.ClientNumber])}, nil
+		},
+		CheckoutFunc: func(ctx context.Context, req *OrderRequest) (*OrderDetail, error) {
+			return &OrderDetail{RequestID: req.RequestID, Status: OrderStatusPending}, nil
+		},
+	}
+
+	stub := &listProductStub{products: []*Product{
+		{Code: "pln-postpaid", CategoryName: "PLN Postpaid"},
+		{Code: "bpjs-kesehatan", CategoryName: "BPJS"},
+	}}
+	catalog := NewProductCatalog(stub.api())
+	catalog.Refresh(ctx)
+
+	cache := NewInquiryCache(
+		WithInquiryCacheLogger(log.New(io.Discard, "", 0)),
+		WithCacheTTL(time.Hour),
+		WithCategoryTTL("BPJS", 0),
+		WithCacheProducts(catalog),
+	)
+	api := Chain(next, cache.Middleware())
+
+	pln := &InquiryRequest{ProductCode: "pln-postpaid", ClientNumber: "2121212"}
+	first, _ := api.Inquiry(ctx, pln)
+	second, _ := api.Inquiry(ctx, pln)
+	if calls["pln-postpaid|2121212"] != 1 || second.SalesPrice != first.SalesPrice {
+		t.Errorf("Inquiry() called %d times, want the second inquiry served from cache", calls["pln-postpaid|2121212"])
+	}
+
+	// the inquiry of another amount or fields is not served from the same cache
+	plnAmount := &InquiryRequest{ProductCode: "pln-postpaid", ClientNumber: "2121212", Amount: 100000, Fields: Fields{{Name: "month", Value: "2"}}}
+	api.Inquiry(ctx, plnAmount)
+	api.Inquiry(ctx, plnAmount)
+	if calls["pln-postpaid|2121212"] != 2 {
+		t.Errorf("Inquiry() with amount and fields called %d times in total, want 2", calls["pln-postpaid|2121212"])
+	}
+
+	refreshed, _ := api.Inquiry(ForceRefresh(ctx), pln)
+	if calls["pln-postpaid|2121212"] != 3 || refreshed.SalesPrice == first.SalesPrice {
+		t.Errorf("Inquiry() with ForceRefresh got = %d, want a new response", refreshed.SalesPrice)
+	}
+
+	// the category without ttl is not cached
+	bpjs := &InquiryRequest{ProductCode: "bpjs-kesehatan", ClientNumber: "0001"}
+	api.Inquiry(ctx, bpjs)
+	api.Inquiry(ctx, bpjs)
+	if calls["bpjs-kesehatan|0001"] != 2 {
+		t.Errorf("Inquiry() BPJS called %d times, want 2", calls["bpjs-kesehatan|0001"])
+	}
+
+	_, err := api.Checkout(ctx, &OrderRequest{RequestID: "order-1", ProductCode: "pln-postpaid", ClientNumber: "2121212"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every cached inquiry of the client number is removed after checkout
+	api.Inquiry(ctx, pln)
+	api.Inquiry(ctx, plnAmount)
+	if calls["pln-postpaid|2121212"] != 5 {
+		t.Errorf("Inquiry() after checkout called %d times, want 5", calls["pln-postpaid|2121212"])
+	}
+}
+
+func TestMemoryInquiryCacheStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryInquiryCacheStore()
+
+	store.Set(ctx, "expired", &InquiryResponse{}, -time.Second)
+	if _, ok, _ := store.Get(ctx, "expired"); ok {
+		t.Errorf("Get() returned the expired entry")
+	}
+
+	resp := &InquiryResponse{SalesPrice: 52500, Fields: Fields{{Name: "month", Value: "2"}}}
+	store.Set(ctx, "key|1", resp, time.Minute)
+	resp.SalesPrice = 0
+	resp.Fields[0].Value = "3"
+
+	got, ok, err := store.Get(ctx, "key|1")
+	if !ok || err != nil || got.SalesPrice != 52500 || got.Fields[0].Value != "2" {
+		t.Errorf("Get() got = %v, %v, %v, want a copy of the cached response", got, ok, err)
+	}
+
+	got.Fields[0].Value = "4"
+	if got, _, _ := store.Get(ctx, "key|1"); got.Fields[0].Value != "2" {
+		t.Errorf("Get() fields got = %v, want the cached fields not shared", got.Fields)
+	}
+
+	if len(store.entries) != 1 {
+		t.Errorf("Set() kept %d entries, want the expired entry removed", len(store.entries))
+	}
+
+	store.Set(ctx, "key|2", &InquiryResponse{}, time.Minute)
+	store.Set(ctx, "other|1", &InquiryResponse{}, time.Minute)
+	store.DeletePrefix(ctx, "key|")
+	if _, ok := store.entries["other|1"]; !ok || len(store.entries) != 1 {
+		t.Errorf("DeletePrefix() kept %d entries, want only other|1", len(store.entries))
+	}
+}
@@ -0,0 +1,29 @@
+package sat
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestField_UnmarshalJSON(t *testing.T) {
+	fields := Fields{{Name: "month", Value: "3"}, {Name: "ref_id", Value: "order-1"}}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got Fields
+	err = json.Unmarshal(data, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 2 || got[0] != fields[0] || got[1] != fields[1] {
+		t.Errorf("UnmarshalJSON() got = %v, want %v", got, fields)
+	}
+
+	err = json.Unmarshal([]byte(`{"name": 1}`), new(Field))
+	if err == nil {
+		t.Errorf("UnmarshalJSON() error = nil, want the type error")
+	}
+}
@@ -0,0 +1,97 @@
+// Package jsonl contains the JSON Lines checkpoint file shared by the batch operations
+package jsonl
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+)
+
+// Load calls fn with every line of the file which can be decoded into a new value made by newValue,
+// the missing file has no line. The last line can be half written when the process is interrupted, it is skipped
+func Load(path string, newValue func() interface{}, fn func(v interface{})) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		v := newValue()
+		if json.Unmarshal(scanner.Bytes(), v) != nil {
+			continue
+		}
+
+		fn(v)
+	}
+
+	return scanner.Err()
+}
+
+// File is the JSON Lines file appended by many goroutines
+type File struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// Open opens the file to append, the half written line is terminated so the next line starts on a new line
+func Open(path string) (*File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if info.Size() == 0 {
+		return &File{f: f}, nil
+	}
+
+	last := make([]byte, 1)
+	_, err = f.ReadAt(last, info.Size()-1)
+	if err == nil && last[0] != '\n' {
+		_, err = f.Write([]byte{'\n'})
+	}
+
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &File{f: f}, nil
+}
+
+// Append writes v as a line and syncs the file, so the line is kept when the process is crashed right after
+func (f *File) Append(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, err = f.f.Write(append(data, '\n'))
+	if err != nil {
+		return err
+	}
+
+	return f.f.Sync()
+}
+
+// Close closes the file
+func (f *File) Close() error {
+	return f.f.Close()
+}
@@ -0,0 +1,183 @@
+package pool
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Bucket allows rate calls per second with burst, the zero rate is unlimited.
+// The rate can be lowered under the limit, and the bucket can be paused until a time
+type Bucket struct {
+	mu     sync.Mutex
+	limit  float64
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	until  time.Time
+}
+
+// NewBucket will return a new bucket, the first burst calls are allowed at once
+func NewBucket(rate float64, burst int) *Bucket {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &Bucket{
+		limit:  rate,
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Rate returns the current rate
+func (b *Bucket) Rate() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.rate
+}
+
+// SetRate changes the rate, the tokens earned using the previous rate are kept
+func (b *Bucket) SetRate(rate float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(time.Now())
+	b.limit = rate
+	b.rate = rate
+}
+
+// SetLimit changes the rate and the burst
+func (b *Bucket) SetLimit(rate float64, burst int) {
+	b.SetRate(rate)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if burst < 1 {
+		burst = 1
+	}
+	b.burst = float64(burst)
+}
+
+// Adjust changes the rate of the limited bucket, it never exceeds the limit
+func (b *Bucket) Adjust(rate float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.limit <= 0 {
+		return
+	}
+
+	b.refill(time.Now())
+	if rate > b.limit {
+		rate = b.limit
+	}
+	b.rate = rate
+}
+
+// Pause holds the calls until the time
+func (b *Bucket) Pause(until time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if until.After(b.until) {
+		b.until = until
+	}
+}
+
+func (b *Bucket) refill(now time.Time) {
+	if b.rate > 0 {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+	}
+
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+}
+
+// reserve takes a token, and returns how long the caller must wait before the token can be used
+func (b *Bucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	var delay time.Duration
+	if now.Before(b.until) {
+		delay = b.until.Sub(now)
+	}
+
+	if b.rate <= 0 {
+		return delay
+	}
+
+	b.refill(now)
+	b.tokens--
+	if b.tokens < 0 {
+		delay += time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+
+	return delay
+}
+
+// Allow takes a token only when it can be used now
+func (b *Bucket) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(b.until) {
+		return false
+	}
+
+	if b.rate <= 0 {
+		return true
+	}
+
+	b.refill(now)
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+	return true
+}
+
+// Wait blocks until a token is available or ctx is done
+func (b *Bucket) Wait(ctx context.Context) error {
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	delay := b.reserve()
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		b.cancel()
+		return ctx.Err()
+	}
+}
+
+// cancel gives back the token taken by the cancelled wait
+func (b *Bucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.rate > 0 {
+		b.tokens++
+	}
+}
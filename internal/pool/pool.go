@@ -0,0 +1,53 @@
+// Package pool contains the worker pool and the token bucket shared by the batch operations
+package pool
+
+import (
+	"context"
+	"sync"
+)
+
+// Run calls fn for every index using concurrency workers, every call waits for the limiter first when it is set.
+// The index not started when ctx is done is skipped, Run returns after every started call is returned
+func Run(ctx context.Context, indexes []int, concurrency int, limiter *Bucket, fn func(i int)) {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				if limiter != nil && limiter.Wait(ctx) != nil {
+					continue
+				}
+
+				fn(i)
+			}
+		}()
+	}
+
+feed:
+	for _, i := range indexes {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// Indexes will return the indexes from 0 to n-1
+func Indexes(n int) []int {
+	indexes := make([]int, n)
+	for i := range indexes {
+		indexes[i] = i
+	}
+
+	return indexes
+}
@@ -0,0 +1,467 @@
+package sat
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// JournalState is the state of the order on the journal
+type JournalState string
+
+const (
+	// JournalPending is for order which the final status is not known yet, including when the checkout outcome is unknown
+	JournalPending JournalState = "PENDING"
+	// JournalSucceeded is for order with final status Success
+	JournalSucceeded JournalState = "SUCCEEDED"
+	// JournalFailed is for order with final status Failed
+	JournalFailed JournalState = "FAILED"
+	// JournalRejected is for checkout rejected by SAT with 4xx except 429, the order is not created
+	JournalRejected JournalState = "REJECTED"
+	// JournalNotSubmitted is for order never received by SAT, example the process is crashed before the checkout is sent
+	JournalNotSubmitted JournalState = "NOT_SUBMITTED"
+)
+
+// Resolved will return true when the state is final
+func (s JournalState) Resolved() bool {
+	return s != JournalPending
+}
+
+// JournalEventType is the type of the journal event
+type JournalEventType string
+
+const (
+	// JournalIntent is written before the checkout is sent
+	JournalIntent JournalEventType = "INTENT"
+	// JournalResponse is written when the checkout is responded
+	JournalResponse JournalEventType = "RESPONSE"
+	// JournalError is written when the checkout is failed
+	JournalError JournalEventType = "ERROR"
+	// JournalCallback is written when the callback is received
+	JournalCallback JournalEventType = "CALLBACK"
+	// JournalStatusCheck is written when the order is checked using CheckStatus
+	JournalStatusCheck JournalEventType = "STATUS_CHECK"
+)
+
+// JournalEvent contains one outcome of the order
+type JournalEvent struct {
+	Type   JournalEventType `json:"type"`
+	At     time.Time        `json:"at"`
+	Status string           `json:"status,omitempty"`
+	Error  string           `json:"error,omitempty"`
+}
+
+// JournalEntry contains the order intent and every outcome recorded for it
+type JournalEntry struct {
+	RequestID string         `json:"request_id"`
+	Request   *OrderRequest  `json:"request,omitempty"`
+	State     JournalState   `json:"state"`
+	Order     *OrderDetail   `json:"order,omitempty"`
+	Events    []JournalEvent `json:"events"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+func (e *JournalEntry) clone() *JournalEntry {
+	c := *e
+	c.Events = append([]JournalEvent(nil), e.Events...)
+	return &c
+}
+
+// JournalStore persists the journal entries. Implement it using database/sql with request_id as the primary key,
+// and index the state to find the unresolved entries
+type JournalStore interface {
+	// Put inserts or replaces the entry by its request id
+	Put(ctx context.Context, entry *JournalEntry) error
+	// Get returns the entry, nil without error when it is not found
+	Get(ctx context.Context, requestID string) (*JournalEntry, error)
+	// Unresolved returns every entry with JournalPending state
+	Unresolved(ctx context.Context) ([]*JournalEntry, error)
+}
+
+// FileJournalStore is a JournalStore appending every entry as a json line into a file,
+// the file is synced on every write. Call Compact to remove the resolved entries from the file
+type FileJournalStore struct {
+	path string
+
+	mu      sync.Mutex
+	file    *os.File
+	entries map[string]*JournalEntry
+}
+
+// NewFileJournalStore will return a new journal store using the file path, the existing entries are loaded
+func NewFileJournalStore(path string) (*FileJournalStore, error) {
+	f := &FileJournalStore{
+		path:    path,
+		entries: map[string]*JournalEntry{},
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		entry := new(JournalEntry)
+		// the last line can be half written when the process is crashed
+		if json.Unmarshal(scanner.Bytes(), entry) != nil {
+			continue
+		}
+
+		f.entries[entry.RequestID] = entry
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	f.file, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	// terminate the half written line, so the next entry starts on a new line
+	if len(b) > 0 && b[len(b)-1] != '\n' {
+		_, err = f.file.Write([]byte{'\n'})
+		if err != nil {
+			f.file.Close()
+			return nil, err
+		}
+	}
+
+	return f, nil
+}
+
+// Put appends the entry into the file
+func (f *FileJournalStore) Put(ctx context.Context, entry *JournalEntry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, err = f.file.Write(append(b, '\n'))
+	if err != nil {
+		return err
+	}
+
+	err = f.file.Sync()
+	if err != nil {
+		return err
+	}
+
+	f.entries[entry.RequestID] = entry.clone()
+	return nil
+}
+
+// Get returns the latest entry of the request id
+func (f *FileJournalStore) Get(ctx context.Context, requestID string) (*JournalEntry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entry, ok := f.entries[requestID]
+	if !ok {
+		return nil, nil
+	}
+
+	return entry.clone(), nil
+}
+
+// Unresolved returns every pending entry sorted by the created time
+func (f *FileJournalStore) Unresolved(ctx context.Context) ([]*JournalEntry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var entries []*JournalEntry
+	for _, entry := range f.entries {
+		if !entry.State.Resolved() {
+			entries = append(entries, entry.clone())
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+
+	return entries, nil
+}
+
+// Compact rewrites the file keeping only the unresolved entries
+func (f *FileJournalStore) Compact(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	buf := &bytes.Buffer{}
+	entries := map[string]*JournalEntry{}
+	for id, entry := range f.entries {
+		if entry.State.Resolved() {
+			continue
+		}
+
+		b, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+
+		buf.Write(append(b, '\n'))
+		entries[id] = entry
+	}
+
+	err := writeFileAtomic(f.path, buf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+
+	f.file.Close()
+	f.file = file
+	f.entries = entries
+	return nil
+}
+
+// Close closes the file
+func (f *FileJournalStore) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.file.Close()
+}
+
+// JournalOption contains field you can configure on the order journal
+type JournalOption struct {
+	logger *log.Logger
+}
+
+var defaultJournalOption = JournalOption{
+	logger: log.New(log.Writer(), "[sat] ", 0),
+}
+
+type JournalOptionFunc func(*JournalOption)
+
+// WithJournalLogger override existing logger
+func WithJournalLogger(logger *log.Logger) JournalOptionFunc {
+	return func(o *JournalOption) {
+		o.logger = logger
+	}
+}
+
+// OrderJournal writes the order intent before the checkout is sent and records every outcome,
+// so the orders are not lost when the process is crashed. Call Recover on startup to reconcile the unresolved orders
+type OrderJournal struct {
+	api   API
+	store JournalStore
+	opt   JournalOption
+
+	mu sync.Mutex
+}
+
+// NewOrderJournal will return a new order journal, api is used by Recover to check the order status
+func NewOrderJournal(api API, store JournalStore, opts ...JournalOptionFunc) *OrderJournal {
+	opt := defaultJournalOption
+	for _, option := range opts {
+		option(&opt)
+	}
+
+	return &OrderJournal{
+		api:   api,
+		store: store,
+		opt:   opt,
+	}
+}
+
+// Middleware will return a Middleware writing the intent before every checkout,
+// and recording the outcome of checkout, check status and callback
+func (j *OrderJournal) Middleware() Middleware {
+	return func(next API) API {
+		return &Decorator{
+			Next: next,
+			CheckoutFunc: func(ctx context.Context, req *OrderRequest) (*OrderDetail, error) {
+				err := j.Intent(ctx, req)
+				if err != nil {
+					return nil, err
+				}
+
+				resp, err := next.Checkout(ctx, req)
+				if err != nil {
+					j.recordError(ctx, req.RequestID, err)
+					return nil, err
+				}
+
+				j.Record(ctx, JournalResponse, resp)
+				return resp, nil
+			},
+			CheckStatusFunc: func(ctx context.Context, requestID string) (*OrderDetail, error) {
+				resp, err := next.CheckStatus(ctx, requestID)
+				if err != nil {
+					return nil, err
+				}
+
+				j.Record(ctx, JournalStatusCheck, resp)
+				return resp, nil
+			},
+			HandleCallbackFunc: func(impl Callback, opts ...CallbackOptionFunc) http.HandlerFunc {
+				return next.HandleCallback(&journalCallback{journal: j, next: impl}, opts...)
+			},
+		}
+	}
+}
+
+// Intent writes the order intent, the checkout must not be sent when it is failed.
+// Writing the intent of the existing pending or created order is ignored, example when the checkout is retried.
+// The rejected or not submitted order is pending again, so Recover checks the retried checkout
+func (j *OrderJournal) Intent(ctx context.Context, req *OrderRequest) error {
+	if req.RequestID == "" {
+		verr := &ValidationError{}
+		verr.add("request_id", "can't be empty, fill it before the journal using EnsureRequestID")
+		return verr
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entry, err := j.store.Get(ctx, req.RequestID)
+	if err != nil {
+		j.opt.logger.Println(err)
+		return err
+	}
+
+	now := time.Now()
+	copied := *req
+	if entry != nil {
+		if entry.State != JournalRejected && entry.State != JournalNotSubmitted {
+			return nil
+		}
+
+		entry.Request = &copied
+		entry.State = JournalPending
+		entry.Events = append(entry.Events, JournalEvent{Type: JournalIntent, At: now})
+		entry.UpdatedAt = now
+	} else {
+		entry = &JournalEntry{
+			RequestID: req.RequestID,
+			Request:   &copied,
+			State:     JournalPending,
+			Events:    []JournalEvent{{Type: JournalIntent, At: now}},
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+	}
+
+	err = j.store.Put(ctx, entry)
+	if err != nil {
+		j.opt.logger.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// Record records the order detail received from the checkout response, callback or check status
+func (j *OrderJournal) Record(ctx context.Context, eventType JournalEventType, order *OrderDetail) {
+	j.update(ctx, order.RequestID, func(entry *JournalEntry) {
+		entry.Order = order
+		entry.Events = append(entry.Events, JournalEvent{Type: eventType, At: time.Now(), Status: order.Status})
+
+		switch order.Status {
+		case OrderStatusSuccess:
+			entry.State = JournalSucceeded
+		case OrderStatusFailed:
+			entry.State = JournalFailed
+		}
+	})
+}
+
+// Recover checks the status of every unresolved order, the order not found on SAT is marked as JournalNotSubmitted.
+// It returns the entries which are still unresolved
+func (j *OrderJournal) Recover(ctx context.Context) ([]*JournalEntry, error) {
+	entries, err := j.store.Unresolved(ctx)
+	if err != nil {
+		j.opt.logger.Println(err)
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		order, err := j.api.CheckStatus(ctx, entry.RequestID)
+		var errR *ErrorResponse
+		switch {
+		case errors.As(err, &errR) && errR.Status() == strconv.Itoa(http.StatusNotFound):
+			j.update(ctx, entry.RequestID, func(entry *JournalEntry) {
+				entry.State = JournalNotSubmitted
+				entry.Events = append(entry.Events, JournalEvent{Type: JournalStatusCheck, At: time.Now(), Error: errR.Error()})
+			})
+		case err != nil:
+			j.opt.logger.Println(err)
+		default:
+			j.Record(ctx, JournalStatusCheck, order)
+		}
+	}
+
+	return j.store.Unresolved(ctx)
+}
+
+func (j *OrderJournal) recordError(ctx context.Context, requestID string, err error) {
+	j.update(ctx, requestID, func(entry *JournalEntry) {
+		entry.Events = append(entry.Events, JournalEvent{Type: JournalError, At: time.Now(), Error: err.Error()})
+
+		// the order is not created when SAT rejects it with 4xx, otherwise example 5xx or 429
+		// the order may be created and it is checked by Recover
+		if isRejected(err) {
+			entry.State = JournalRejected
+		}
+	})
+}
+
+func (j *OrderJournal) update(ctx context.Context, requestID string, fn func(entry *JournalEntry)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entry, err := j.store.Get(ctx, requestID)
+	if err != nil {
+		j.opt.logger.Println(err)
+		return
+	}
+
+	now := time.Now()
+	if entry == nil {
+		// the order is not checked out through the journal, example the callback of the previous orders
+		entry = &JournalEntry{RequestID: requestID, State: JournalPending, CreatedAt: now}
+	}
+
+	fn(entry)
+	entry.UpdatedAt = now
+
+	err = j.store.Put(ctx, entry)
+	if err != nil {
+		j.opt.logger.Println(err)
+	}
+}
+
+// journalCallback records the callback before calling the callback implementation
+type journalCallback struct {
+	journal *OrderJournal
+	next    Callback
+}
+
+// Do records the callback and calls the next callback
+func (c *journalCallback) Do(ctx context.Context, request *OrderDetail) error {
+	c.journal.Record(ctx, JournalCallback, request)
+	return c.next.Do(ctx, request)
+}
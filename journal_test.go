@@ -0,0 +1,184 @@
+package sat
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOrderJournal(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+
+	store, err := NewFileJournalStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	crash := errors.New("connection reset")
+	next := &Decorator{
+		CheckoutFunc: func(ctx context.Context, req *OrderRequest) (*OrderDetail, error) {
+			switch req.RequestID {
+			case "order-1":
+				return &OrderDetail{RequestID: req.RequestID, Status: OrderStatusPending}, nil
+			case "order-2":
+				return nil, &ErrorResponse{Errors: []*ErrorObject{{Status: "400", Code: "P01", Detail: "product is not found"}}}
+			case "order-6":
+				// SAT creates and charges the order before failing
+				return nil, &ErrorResponse{Errors: []*ErrorObject{{Status: "500", Code: "S00", Detail: "internal error"}}}
+			default:
+				return nil, crash
+			}
+		},
+		CheckStatusFunc: func(ctx context.Context, requestID string) (*OrderDetail, error) {
+			switch requestID {
+			case "order-1", "order-6":
+				return &OrderDetail{RequestID: requestID, Status: OrderStatusSuccess}, nil
+			case "order-3":
+				return nil, &ErrorResponse{Errors: []*ErrorObject{{Status: "404", Code: "O01", Detail: "order is not found"}}}
+			default:
+				return &OrderDetail{RequestID: requestID, Status: OrderStatusPending}, nil
+			}
+		},
+	}
+
+	journal := NewOrderJournal(next, store, WithJournalLogger(log.New(io.Discard, "", 0)))
+	api := Chain(next, journal.Middleware())
+	for _, id := range []string{"order-1", "order-2", "order-3", "order-6"} {
+		api.Checkout(ctx, &OrderRequest{RequestID: id, ProductCode: "telkomsel-10k"})
+	}
+
+	// the process is crashed after the intent is written
+	err = journal.Intent(ctx, &OrderRequest{RequestID: "order-4", ProductCode: "telkomsel-10k"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	// a half written line is ignored
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	f.Write([]byte(`{"request_id":"order-5","sta`))
+	f.Close()
+
+	store, err = NewFileJournalStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	journal = NewOrderJournal(next, store, WithJournalLogger(log.New(io.Discard, "", 0)))
+	unresolved, err := journal.Recover(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(unresolved) != 1 || unresolved[0].RequestID != "order-4" {
+		t.Errorf("Recover() got = %v, want order-4 still pending", unresolved)
+	}
+
+	want := map[string]JournalState{
+		"order-1": JournalSucceeded,
+		"order-2": JournalRejected,
+		"order-3": JournalNotSubmitted,
+		"order-4": JournalPending,
+		"order-6": JournalSucceeded,
+	}
+	for id, state := range want {
+		entry, err := store.Get(ctx, id)
+		if err != nil || entry == nil || entry.State != state {
+			t.Errorf("Get(%s) got = %v, %v, want %s", id, entry, err, state)
+		}
+	}
+
+	entry, _ := store.Get(ctx, "order-1")
+	if len(entry.Events) != 3 || entry.Events[0].Type != JournalIntent || entry.Request.ProductCode != "telkomsel-10k" {
+		t.Errorf("Get(order-1) events = %v", entry.Events)
+	}
+
+	store.Close()
+	store, err = NewFileJournalStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if entry, _ := store.Get(ctx, "order-3"); entry == nil || entry.State != JournalNotSubmitted {
+		t.Errorf("Get(order-3) after reopen got = %v, want %s", entry, JournalNotSubmitted)
+	}
+
+	err = store.Compact(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store.Close()
+	store, err = NewFileJournalStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	if entry, _ := store.Get(ctx, "order-1"); entry != nil {
+		t.Errorf("Compact() kept the resolved entry")
+	}
+
+	if entry, _ := store.Get(ctx, "order-4"); entry == nil {
+		t.Errorf("Compact() removed the unresolved entry")
+	}
+}
+
+func TestOrderJournal_Retry(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileJournalStore(filepath.Join(t.TempDir(), "journal.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	attempts := map[string]int{}
+	next := &Decorator{
+		CheckoutFunc: func(ctx context.Context, req *OrderRequest) (*OrderDetail, error) {
+			attempts[req.RequestID]++
+			if req.RequestID == "order-1" {
+				return &OrderDetail{RequestID: req.RequestID, Status: OrderStatusSuccess}, nil
+			}
+
+			// the first attempt is rejected, the retry is left unknown
+			if attempts[req.RequestID] == 1 {
+				return nil, &ErrorResponse{Errors: []*ErrorObject{{Status: "400", Code: "P01", Detail: "product is not active"}}}
+			}
+
+			return nil, &ErrorResponse{Errors: []*ErrorObject{{Status: "500", Code: "S00", Detail: "internal error"}}}
+		},
+		CheckStatusFunc: func(ctx context.Context, requestID string) (*OrderDetail, error) {
+			return &OrderDetail{RequestID: requestID, Status: OrderStatusSuccess}, nil
+		},
+	}
+
+	journal := NewOrderJournal(next, store, WithJournalLogger(log.New(io.Discard, "", 0)))
+	api := Chain(next, journal.Middleware())
+	for i := 0; i < 2; i++ {
+		api.Checkout(ctx, &OrderRequest{RequestID: "order-1", ProductCode: "telkomsel-10k"})
+		api.Checkout(ctx, &OrderRequest{RequestID: "order-2", ProductCode: "telkomsel-10k"})
+	}
+
+	if entry, _ := store.Get(ctx, "order-1"); entry == nil || entry.State != JournalSucceeded || entry.Events[2].Type == JournalIntent {
+		t.Errorf("Get(order-1) got = %v, want the retry of the succeeded order ignored", entry)
+	}
+
+	entry, _ := store.Get(ctx, "order-2")
+	if entry == nil || entry.State != JournalPending || entry.Events[2].Type != JournalIntent {
+		t.Fatalf("Get(order-2) got = %v, want the retried rejected order pending again", entry)
+	}
+
+	unresolved, err := journal.Recover(ctx)
+	if err != nil || len(unresolved) != 0 {
+		t.Errorf("Recover() got = %v, %v, want nothing unresolved", unresolved, err)
+	}
+
+	if entry, _ := store.Get(ctx, "order-2"); entry.State != JournalSucceeded {
+		t.Errorf("Get(order-2) after Recover got = %s, want %s", entry.State, JournalSucceeded)
+	}
+}
@@ -0,0 +1,467 @@
+package sat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Reservation contains the expected price reserved for an order until its final status is known
+type Reservation struct {
+	RequestID   string    `json:"request_id"`
+	ProductCode string    `json:"product_code"`
+	Amount      int64     `json:"amount"`
+	ReservedAt  time.Time `json:"reserved_at"`
+}
+
+// LedgerStore persists the pending reservations, so they survive restarts
+type LedgerStore interface {
+	// Load returns the saved reservations, nil without error when nothing is saved yet
+	Load(ctx context.Context) ([]*Reservation, error)
+	Save(ctx context.Context, reservations []*Reservation) error
+}
+
+// FileLedgerStore is a LedgerStore saving the reservations as json into a file
+type FileLedgerStore struct {
+	path string
+}
+
+// NewFileLedgerStore will return a new ledger store using the file path
+func NewFileLedgerStore(path string) *FileLedgerStore {
+	return &FileLedgerStore{path: path}
+}
+
+// Load reads the reservations from the file
+func (f *FileLedgerStore) Load(ctx context.Context) ([]*Reservation, error) {
+	b, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var reservations []*Reservation
+	err = json.Unmarshal(b, &reservations)
+	if err != nil {
+		return nil, err
+	}
+
+	return reservations, nil
+}
+
+// Save writes the reservations into the file
+func (f *FileLedgerStore) Save(ctx context.Context, reservations []*Reservation) error {
+	b, err := json.Marshal(reservations)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(f.path, b)
+}
+
+// LedgerOption contains field you can configure on the ledger
+type LedgerOption struct {
+	logger            *log.Logger
+	store             LedgerStore
+	products          ProductLookup
+	reconcileInterval time.Duration
+}
+
+var defaultLedgerOption = LedgerOption{
+	logger:            log.New(log.Writer(), "[sat] ", 0),
+	reconcileInterval: 5 * time.Minute,
+}
+
+type LedgerOptionFunc func(*LedgerOption)
+
+// WithLedgerLogger override existing logger
+func WithLedgerLogger(logger *log.Logger) LedgerOptionFunc {
+	return func(o *LedgerOption) {
+		o.logger = logger
+	}
+}
+
+// WithLedgerStore set the store persisting the pending reservations
+func WithLedgerStore(store LedgerStore) LedgerOptionFunc {
+	return func(o *LedgerOption) {
+		o.store = store
+	}
+}
+
+// WithLedgerProducts set the product lookup used by Middleware to get the expected price,
+// when it is not set only the checkout with Amount is reserved
+func WithLedgerProducts(products ProductLookup) LedgerOptionFunc {
+	return func(o *LedgerOption) {
+		o.products = products
+	}
+}
+
+// WithReconcileInterval set how often the balance and the pending reservations are reconciled,
+// the background reconciliation is disabled when it is not positive
+func WithReconcileInterval(reconcileInterval time.Duration) LedgerOptionFunc {
+	return func(o *LedgerOption) {
+		o.reconcileInterval = reconcileInterval
+	}
+}
+
+// Ledger reserves the expected price of every in-flight order, so the concurrent orders can't exceed the balance.
+// The reservation is settled when the order is succeeded and released when the order is failed.
+// The settled amount is deducted locally until the next balance is fetched using Account
+type Ledger struct {
+	api API
+	opt LedgerOption
+
+	saveMu       sync.Mutex
+	mu           sync.Mutex
+	saldo        int64
+	known        bool
+	settled      int64
+	reservations map[string]*Reservation
+
+	stopOnce sync.Once
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// NewLedger will return a new ledger, call Start to load the reservations and reconcile them periodically
+func NewLedger(api API, opts ...LedgerOptionFunc) *Ledger {
+	opt := defaultLedgerOption
+	for _, option := range opts {
+		option(&opt)
+	}
+
+	return &Ledger{
+		api:          api,
+		opt:          opt,
+		reservations: map[string]*Reservation{},
+	}
+}
+
+// Start loads the persisted reservations, reconciles them and keeps reconciling in background
+// until Stop is called or ctx is done. The error of the first reconciliation is returned
+func (l *Ledger) Start(ctx context.Context) error {
+	if l.opt.store != nil {
+		reservations, err := l.opt.store.Load(ctx)
+		if err != nil {
+			l.opt.logger.Println(err)
+			return err
+		}
+
+		l.mu.Lock()
+		for _, r := range reservations {
+			l.reservations[r.RequestID] = r
+		}
+		l.mu.Unlock()
+	}
+
+	err := l.Reconcile(ctx)
+	if l.opt.reconcileInterval <= 0 {
+		return err
+	}
+
+	ctx, l.cancel = context.WithCancel(ctx)
+	l.done = make(chan struct{})
+	go l.loop(ctx)
+
+	return err
+}
+
+// Stop stops the background reconciliation
+func (l *Ledger) Stop() {
+	l.stopOnce.Do(func() {
+		if l.cancel == nil {
+			return
+		}
+
+		l.cancel()
+		<-l.done
+	})
+}
+
+func (l *Ledger) loop(ctx context.Context) {
+	defer close(l.done)
+
+	t := time.NewTicker(l.opt.reconcileInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		l.Reconcile(ctx)
+	}
+}
+
+// Reconcile fetches the balance using Account, then resolves every pending reservation using CheckStatus,
+// example when the callback is missed. The reservation of the order not found by SAT is released
+func (l *Ledger) Reconcile(ctx context.Context) error {
+	account, err := l.api.Account(ctx)
+	if err != nil {
+		l.opt.logger.Println(err)
+		return err
+	}
+
+	l.mu.Lock()
+	l.saldo = account.Saldo
+	l.known = true
+	l.settled = 0
+	pending := make([]string, 0, len(l.reservations))
+	for id := range l.reservations {
+		pending = append(pending, id)
+	}
+	l.mu.Unlock()
+
+	for _, id := range pending {
+		order, err := l.api.CheckStatus(ctx, id)
+		if errorStatus(err) == "404" {
+			l.Release(ctx, id)
+			continue
+		}
+
+		// the order may be created when SAT can't answer, keep it until the next reconciliation
+		if err != nil {
+			l.opt.logger.Println(err)
+			continue
+		}
+
+		l.Apply(ctx, order)
+	}
+
+	return nil
+}
+
+// Reserve reserves the amount for the order, it returns *InsufficientBalanceError when the amount exceeds the available balance.
+// Reserving the same request id again is ignored
+func (l *Ledger) Reserve(ctx context.Context, requestID, productCode string, amount int64) error {
+	l.mu.Lock()
+	if _, ok := l.reservations[requestID]; ok {
+		l.mu.Unlock()
+		return nil
+	}
+
+	if available := l.available(); l.known && amount > available {
+		l.mu.Unlock()
+		return &InsufficientBalanceError{Balance: available, Price: amount}
+	}
+
+	l.reservations[requestID] = &Reservation{
+		RequestID:   requestID,
+		ProductCode: productCode,
+		Amount:      amount,
+		ReservedAt:  time.Now(),
+	}
+	l.mu.Unlock()
+
+	l.save(ctx)
+	return nil
+}
+
+// Settle removes the reservation and deducts the amount locally, when the order is succeeded
+func (l *Ledger) Settle(ctx context.Context, requestID string) {
+	l.resolve(ctx, requestID, true)
+}
+
+// Release removes the reservation without deducting the amount, when the order is failed
+func (l *Ledger) Release(ctx context.Context, requestID string) {
+	l.resolve(ctx, requestID, false)
+}
+
+// Apply settles or releases the reservation based on the final order status, the pending order is ignored
+func (l *Ledger) Apply(ctx context.Context, order *OrderDetail) {
+	switch order.Status {
+	case OrderStatusSuccess:
+		l.Settle(ctx, order.RequestID)
+	case OrderStatusFailed:
+		l.Release(ctx, order.RequestID)
+	}
+}
+
+// Balance returns the last fetched balance minus the settled amount since then
+func (l *Ledger) Balance() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.saldo - l.settled
+}
+
+// Reserved returns the total amount of the pending reservations
+func (l *Ledger) Reserved() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.reserved()
+}
+
+// Available returns the balance minus the pending reservations
+func (l *Ledger) Available() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.available()
+}
+
+// Reservations returns every pending reservation sorted by the reserved time
+func (l *Ledger) Reservations() []*Reservation {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.list()
+}
+
+// Middleware will return a Middleware reserving the expected price before the checkout,
+// and applying the order status of checkout, check status and callback to the ledger
+func (l *Ledger) Middleware() Middleware {
+	return func(next API) API {
+		return &Decorator{
+			Next: next,
+			CheckoutFunc: func(ctx context.Context, req *OrderRequest) (*OrderDetail, error) {
+				reserved, err := l.reserveOrder(ctx, req)
+				if err != nil {
+					return nil, err
+				}
+
+				resp, err := next.Checkout(ctx, req)
+				if err != nil {
+					// the order is not created when SAT rejects it or the request is not sent,
+					// otherwise example 5xx or timeout it may be created and it is reconciled later
+					if reserved && (isRejected(err) || isNotSent(err)) {
+						l.Release(ctx, req.RequestID)
+					}
+
+					return nil, err
+				}
+
+				l.Apply(ctx, resp)
+				return resp, nil
+			},
+			CheckStatusFunc: func(ctx context.Context, requestID string) (*OrderDetail, error) {
+				resp, err := next.CheckStatus(ctx, requestID)
+				if err != nil {
+					return nil, err
+				}
+
+				l.Apply(ctx, resp)
+				return resp, nil
+			},
+			HandleCallbackFunc: func(impl Callback, opts ...CallbackOptionFunc) http.HandlerFunc {
+				return next.HandleCallback(&ledgerCallback{ledger: l, next: impl}, opts...)
+			},
+		}
+	}
+}
+
+func (l *Ledger) reserveOrder(ctx context.Context, req *OrderRequest) (bool, error) {
+	if req.RequestID == "" {
+		verr := &ValidationError{}
+		verr.add("request_id", "can't be empty, fill it before the ledger using EnsureRequestID")
+		return false, verr
+	}
+
+	amount := req.Amount
+	if amount <= 0 {
+		if l.opt.products == nil {
+			return false, nil
+		}
+
+		product, err := l.opt.products.Get(ctx, req.ProductCode)
+		if err != nil {
+			// let SAT decide when the price is unknown
+			l.opt.logger.Println(err)
+			return false, nil
+		}
+
+		amount = product.SalesPrice
+	}
+
+	err := l.Reserve(ctx, req.RequestID, req.ProductCode, amount)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (l *Ledger) resolve(ctx context.Context, requestID string, settle bool) {
+	l.mu.Lock()
+	r, ok := l.reservations[requestID]
+	if !ok {
+		l.mu.Unlock()
+		return
+	}
+
+	delete(l.reservations, requestID)
+	if settle {
+		l.settled += r.Amount
+	}
+	l.mu.Unlock()
+
+	l.save(ctx)
+}
+
+func (l *Ledger) save(ctx context.Context) {
+	if l.opt.store == nil {
+		return
+	}
+
+	// serialize the saves, so the last saved reservations are the latest
+	l.saveMu.Lock()
+	defer l.saveMu.Unlock()
+
+	l.mu.Lock()
+	reservations := l.list()
+	l.mu.Unlock()
+
+	err := l.opt.store.Save(ctx, reservations)
+	if err != nil {
+		l.opt.logger.Println(err)
+	}
+}
+
+func (l *Ledger) list() []*Reservation {
+	reservations := make([]*Reservation, 0, len(l.reservations))
+	for _, r := range l.reservations {
+		reservations = append(reservations, r)
+	}
+
+	sort.Slice(reservations, func(i, j int) bool {
+		return reservations[i].ReservedAt.Before(reservations[j].ReservedAt)
+	})
+
+	return reservations
+}
+
+func (l *Ledger) reserved() int64 {
+	var reserved int64
+	for _, r := range l.reservations {
+		reserved += r.Amount
+	}
+
+	return reserved
+}
+
+func (l *Ledger) available() int64 {
+	return l.saldo - l.settled - l.reserved()
+}
+
+// ledgerCallback applies the callback to the ledger before calling the callback implementation
+type ledgerCallback struct {
+	ledger *Ledger
+	next   Callback
+}
+
+// Do applies the order status and calls the next callback
+func (c *ledgerCallback) Do(ctx context.Context, request *OrderDetail) error {
+	c.ledger.Apply(ctx, request)
+	return c.next.Do(ctx, request)
+}
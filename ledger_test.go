@@ -0,0 +1,157 @@
+package sat
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLedger(t *testing.T) {
+	ctx := context.Background()
+	store := NewFileLedgerStore(filepath.Join(t.TempDir(), "ledger.json"))
+
+	var mu sync.Mutex
+	statuses := map[string]string{}
+	next := &Decorator{
+		AccountFunc: func(ctx context.Context) (*Account, error) {
+			return &Account{Saldo: 25000}, nil
+		},
+		CheckoutFunc: func(ctx context.Context, req *OrderRequest) (*OrderDetail, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			statuses[req.RequestID] = OrderStatusPending
+			return &OrderDetail{RequestID: req.RequestID, Status: OrderStatusPending}, nil
+		},
+		CheckStatusFunc: func(ctx context.Context, requestID string) (*OrderDetail, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			return &OrderDetail{RequestID: requestID, Status: statuses[requestID]}, nil
+		},
+	}
+
+	stub := &listProductStub{products: []*Product{{Code: "telkomsel-10k", SalesPrice: 10200}}}
+	catalog := NewProductCatalog(stub.api())
+	catalog.Refresh(ctx)
+
+	newLedger := func() *Ledger {
+		return NewLedger(next,
+			WithLedgerLogger(log.New(io.Discard, "", 0)),
+			WithLedgerStore(store),
+			WithLedgerProducts(catalog),
+			WithReconcileInterval(time.Hour),
+		)
+	}
+
+	ledger := newLedger()
+	err := ledger.Start(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api := Chain(next, ledger.Middleware())
+	for _, id := range []string{"order-1", "order-2"} {
+		_, err = api.Checkout(ctx, &OrderRequest{RequestID: id, ProductCode: "telkomsel-10k"})
+		if err != nil {
+			t.Fatalf("Checkout(%s) error = %v", id, err)
+		}
+	}
+
+	_, err = api.Checkout(ctx, &OrderRequest{RequestID: "order-3", ProductCode: "telkomsel-10k"})
+	var errB *InsufficientBalanceError
+	if !errors.As(err, &errB) || errB.Balance != 4600 {
+		t.Errorf("Checkout() error = %v, want insufficient available balance", err)
+	}
+
+	if ledger.Reserved() != 20400 || ledger.Available() != 4600 {
+		t.Errorf("Reserved() = %d, Available() = %d", ledger.Reserved(), ledger.Available())
+	}
+	ledger.Stop()
+
+	// the orders are finalized while the service is down
+	statuses["order-1"] = OrderStatusSuccess
+	statuses["order-2"] = OrderStatusFailed
+
+	ledger = newLedger()
+	err = ledger.Start(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ledger.Stop()
+
+	if ledger.Reserved() != 0 || ledger.Balance() != 25000-10200 || ledger.Available() != 25000-10200 {
+		t.Errorf("after reconcile Reserved() = %d, Balance() = %d, Available() = %d", ledger.Reserved(), ledger.Balance(), ledger.Available())
+	}
+
+	reservations, err := store.Load(ctx)
+	if err != nil || len(reservations) != 0 {
+		t.Errorf("store Load() got = %v, %v, want empty", reservations, err)
+	}
+}
+
+func TestLedger_Release(t *testing.T) {
+	ctx := context.Background()
+	errs := map[string]error{
+		"order-cancelled": context.Canceled,
+		"order-circuit":   &CircuitOpenError{Operation: OperationCheckout},
+		"order-limited":   ErrRateLimited,
+		"order-balance":   &InsufficientBalanceError{Balance: 5000, Price: 10000},
+		"order-rejected":  &ErrorResponse{Errors: []*ErrorObject{{Status: "400", Code: "P00", Detail: "product is inactive"}}},
+		"order-5xx":       &ErrorResponse{Errors: []*ErrorObject{{Status: "500", Code: "S00", Detail: "internal error"}}},
+		"order-429":       &ErrorResponse{Errors: []*ErrorObject{{Status: "429", Code: "R00", Detail: "too many requests"}}},
+	}
+
+	next := &Decorator{
+		AccountFunc: func(ctx context.Context) (*Account, error) {
+			return &Account{Saldo: 100000}, nil
+		},
+		CheckoutFunc: func(ctx context.Context, req *OrderRequest) (*OrderDetail, error) {
+			return nil, errs[req.RequestID]
+		},
+		CheckStatusFunc: func(ctx context.Context, requestID string) (*OrderDetail, error) {
+			if requestID == "order-5xx" || requestID == "order-cancelled" {
+				return nil, &ErrorResponse{Errors: []*ErrorObject{{Status: "404", Code: "O01", Detail: "order is not found"}}}
+			}
+
+			return nil, &ErrorResponse{Errors: []*ErrorObject{{Status: "503", Code: "S01", Detail: "service unavailable"}}}
+		},
+	}
+
+	// the zero interval disables the background reconciliation instead of panic
+	ledger := NewLedger(next, WithLedgerLogger(log.New(io.Discard, "", 0)), WithReconcileInterval(0))
+	err := ledger.Start(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ledger.Stop()
+
+	api := Chain(next, ledger.Middleware())
+	for id := range errs {
+		_, err := api.Checkout(ctx, &OrderRequest{RequestID: id, ProductCode: "telkomsel-10k", Amount: 10000})
+		if err == nil {
+			t.Fatalf("Checkout(%s) error = nil", id)
+		}
+	}
+
+	reservations := ledger.Reservations()
+	if len(reservations) != 3 || ledger.Reserved() != 30000 {
+		t.Errorf("Reservations() got = %d, want only the cancelled, 5xx and 429 orders kept", len(reservations))
+	}
+
+	// the cancelled and 5xx orders are not found by SAT, the 429 order can't be checked yet
+	err = ledger.Reconcile(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reservations = ledger.Reservations()
+	if len(reservations) != 1 || reservations[0].RequestID != "order-429" {
+		t.Errorf("Reservations() after reconcile got = %v, want order-429", reservations)
+	}
+}
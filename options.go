@@ -3,6 +3,7 @@ package sat
 import (
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/tokopedia/golang-sat/signature"
 )
@@ -19,6 +20,11 @@ type Option struct {
 	isDebug          bool
 	accessTokenURL   string
 	satBaseURL       string
+	inquiryTTL       time.Duration
+	requestIDGen     RequestIDGenerator
+	duplicateGuard   int
+	rateLimiter      *RateLimiter
+	circuitBreaker   *CircuitBreaker
 }
 
 var defaultOption = Option{
@@ -28,6 +34,7 @@ var defaultOption = Option{
 	isDebug:        false,
 	accessTokenURL: ACCESS_TOKEN_URL,
 	satBaseURL:     PLAYGROUND_SAT_BASE_URL,
+	inquiryTTL:     DEFAULT_INQUIRY_TTL,
 }
 
 type ClientOptionFunc func(*Option)
@@ -80,3 +87,42 @@ func WithAccessTokenURL(accessTokenURL string) ClientOptionFunc {
 		o.accessTokenURL = accessTokenURL
 	}
 }
+
+// WithInquiryPaymentTTL set how long the inquiry result can be paid using PayInquiry
+func WithInquiryPaymentTTL(inquiryTTL time.Duration) ClientOptionFunc {
+	return func(o *Option) {
+		o.inquiryTTL = inquiryTTL
+	}
+}
+
+// WithRequestIDGenerator fill the empty RequestID of Checkout and PayInquiry using the generator,
+// and validate the RequestID given by the caller against the length and charset rules.
+// The middlewares wrapping the client see the empty RequestID, use EnsureRequestID for them
+func WithRequestIDGenerator(generator RequestIDGenerator) ClientOptionFunc {
+	return func(o *Option) {
+		o.requestIDGen = generator
+	}
+}
+
+// WithDuplicateGuard reject Checkout with the RequestID submitted within the last size checkouts,
+// the RequestID of the failed checkout can be submitted again
+func WithDuplicateGuard(size int) ClientOptionFunc {
+	return func(o *Option) {
+		o.duplicateGuard = size
+	}
+}
+
+// WithRateLimit limits the calls of every operation using the rate limiter,
+// share the rate limiter between clients to share the limits
+func WithRateLimit(rateLimiter *RateLimiter) ClientOptionFunc {
+	return func(o *Option) {
+		o.rateLimiter = rateLimiter
+	}
+}
+
+// WithCircuitBreaker rejects the calls of the failing operation immediately using the circuit breaker
+func WithCircuitBreaker(circuitBreaker *CircuitBreaker) ClientOptionFunc {
+	return func(o *Option) {
+		o.circuitBreaker = circuitBreaker
+	}
+}
@@ -30,3 +30,12 @@ type OrderDetail struct {
 	VoucherCode       string     `jsonapi:"attr,voucher_code"`
 	SerialNumber      string     `jsonapi:"attr,serial_number"`
 }
+
+const (
+	// OrderStatusPending is for order still processed by SAT
+	OrderStatusPending = "Pending"
+	// OrderStatusSuccess is for order fulfilled successfully
+	OrderStatusSuccess = "Success"
+	// OrderStatusFailed is for order failed, the error code will be exposed
+	OrderStatusFailed = "Failed"
+)
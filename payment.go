@@ -0,0 +1,226 @@
+package sat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrInquiryExpired is returned by PayInquiry when the inquiry is older than the inquiry TTL,
+// or when the inquiry time is unknown. Do the inquiry again to get the latest bill
+var ErrInquiryExpired = errors.New(INQUIRY_EXPIRED)
+
+// PayInquiryOption contains field you can configure on the order built from the inquiry
+type PayInquiryOption struct {
+	requestID   string
+	amount      int64
+	downlineID  string
+	fields      Fields
+	inquiredAt  time.Time
+	callOptions []CallOptionFunc
+	api         API
+}
+
+type PayInquiryOptionFunc func(*PayInquiryOption)
+
+// WithPayRequestID set the request id of the order, it must be unique for each transaction
+func WithPayRequestID(requestID string) PayInquiryOptionFunc {
+	return func(o *PayInquiryOption) {
+		o.requestID = requestID
+	}
+}
+
+// WithPayAmount set the amount to pay, it must be within the payment range of the inquiry
+func WithPayAmount(amount int64) PayInquiryOptionFunc {
+	return func(o *PayInquiryOption) {
+		o.amount = amount
+	}
+}
+
+// WithPayDownlineID set the downline id of the order
+func WithPayDownlineID(downlineID string) PayInquiryOptionFunc {
+	return func(o *PayInquiryOption) {
+		o.downlineID = downlineID
+	}
+}
+
+// WithPayFields add the fields to the order, the field with the same name as the inquiry field overrides it
+func WithPayFields(fields ...Field) PayInquiryOptionFunc {
+	return func(o *PayInquiryOption) {
+		o.fields = append(o.fields, fields...)
+	}
+}
+
+// WithInquiredAt set the time of the inquiry, example when the inquiry is restored from your storage.
+// By default the time is recorded by Client.Inquiry
+func WithInquiredAt(inquiredAt time.Time) PayInquiryOptionFunc {
+	return func(o *PayInquiryOption) {
+		o.inquiredAt = inquiredAt
+	}
+}
+
+// WithPayCallOptions set the call options of the checkout, example WithCorrelationID
+func WithPayCallOptions(callOptions ...CallOptionFunc) PayInquiryOptionFunc {
+	return func(o *PayInquiryOption) {
+		o.callOptions = append(o.callOptions, callOptions...)
+	}
+}
+
+// WithPayAPI set the API to checkout the order through, example the client wrapped by Chain so the middlewares
+// like the ledger and the journal see the order. By default the order is checked out by the client directly
+func WithPayAPI(api API) PayInquiryOptionFunc {
+	return func(o *PayInquiryOption) {
+		o.api = api
+	}
+}
+
+// OrderFromInquiry will return the order paying the inquiry. The product code, client number and fields are copied,
+// and the RefID is carried as ref_id field. It returns *ValidationError when the amount breaks the payment rules
+func OrderFromInquiry(inq *InquiryResponse, opts ...PayInquiryOptionFunc) (*OrderRequest, error) {
+	opt := PayInquiryOption{}
+	for _, option := range opts {
+		option(&opt)
+	}
+
+	return orderFromInquiry(inq, &opt)
+}
+
+// PayInquiry builds the order from the inquiry using OrderFromInquiry and checkouts it through WithPayAPI.
+// The request id is generated when it is not set and WithRequestIDGenerator is set.
+// It returns ErrInquiryExpired when the inquiry is older than the inquiry TTL
+func (c *Client) PayInquiry(ctx context.Context, inq *InquiryResponse, opts ...PayInquiryOptionFunc) (*OrderDetail, error) {
+	opt := PayInquiryOption{}
+	for _, option := range opts {
+		option(&opt)
+	}
+
+	inquiredAt := opt.inquiredAt
+	if inquiredAt.IsZero() {
+		inquiredAt = c.inquiries.inquiredAt(inq)
+	}
+
+	if inquiredAt.IsZero() || time.Since(inquiredAt) > c.inquiryTTL {
+		return nil, ErrInquiryExpired
+	}
+
+	if opt.requestID == "" && c.requestIDGenerator != nil {
+		opt.requestID = c.requestIDGenerator.Generate()
+	}
+
+	req, err := orderFromInquiry(inq, &opt)
+	if err != nil {
+		newCallOption(ctx, opt.callOptions).log(c.logger, err)
+		return nil, err
+	}
+
+	if opt.api == nil {
+		return c.Checkout(ctx, req, opt.callOptions...)
+	}
+
+	return opt.api.Checkout(ctx, req, opt.callOptions...)
+}
+
+func orderFromInquiry(inq *InquiryResponse, opt *PayInquiryOption) (*OrderRequest, error) {
+	verr := &ValidationError{}
+	if opt.requestID == "" {
+		verr.add("request_id", "can't be empty")
+	}
+
+	if inq.ProductCode == "" {
+		verr.add("product_code", "can't be empty")
+	}
+
+	if inq.ClientNumber == "" {
+		verr.add("client_number", "can't be empty")
+	}
+
+	amount := opt.amount
+	switch {
+	case amount < 0:
+		verr.add("amount", "can't be negative")
+	case amount > 0 && inq.MinPayment > 0 && amount < inq.MinPayment:
+		verr.add("amount", fmt.Sprintf("can't be less than min payment %d", inq.MinPayment))
+	case amount > 0 && inq.MaxPayment > 0 && amount > inq.MaxPayment:
+		verr.add("amount", fmt.Sprintf("can't be more than max payment %d", inq.MaxPayment))
+	case amount > 0 && inq.MinAmount > 0 && amount < inq.MinAmount:
+		verr.add("amount", fmt.Sprintf("can't be less than min amount %d", inq.MinAmount))
+	}
+
+	if err := verr.err(); err != nil {
+		return nil, err
+	}
+
+	fields := append(Fields(nil), inq.Fields...)
+	if inq.RefID != "" {
+		fields = setField(fields, Field{Name: REF_ID_FIELD, Value: inq.RefID})
+	}
+
+	for _, f := range opt.fields {
+		fields = setField(fields, f)
+	}
+
+	return &OrderRequest{
+		RequestID:    opt.requestID,
+		ProductCode:  inq.ProductCode,
+		ClientNumber: inq.ClientNumber,
+		Amount:       amount,
+		Fields:       fields,
+		DownlineID:   opt.downlineID,
+	}, nil
+}
+
+func setField(fields Fields, field Field) Fields {
+	for i := range fields {
+		if fields[i].Name == field.Name {
+			fields[i] = field
+			return fields
+		}
+	}
+
+	return append(fields, field)
+}
+
+// inquiryClock records the time of every inquiry until it is expired. The inquiry is identified by its bill,
+// so the later inquiry of another bill of the same client number doesn't refresh the earlier one
+type inquiryClock struct {
+	mu    sync.Mutex
+	times map[inquiryID]time.Time
+}
+
+type inquiryID struct {
+	id           string
+	productCode  string
+	clientNumber string
+	refID        string
+	salesPrice   int64
+}
+
+func newInquiryID(inq *InquiryResponse) inquiryID {
+	return inquiryID{inq.ID, inq.ProductCode, inq.ClientNumber, inq.RefID, inq.SalesPrice}
+}
+
+func (i *inquiryClock) record(inq *InquiryResponse, now time.Time, ttl time.Duration) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.times == nil {
+		i.times = map[inquiryID]time.Time{}
+	}
+
+	for key, t := range i.times {
+		if now.Sub(t) > ttl {
+			delete(i.times, key)
+		}
+	}
+
+	i.times[newInquiryID(inq)] = now
+}
+
+func (i *inquiryClock) inquiredAt(inq *InquiryResponse) time.Time {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.times[newInquiryID(inq)]
+}
@@ -0,0 +1,143 @@
+package sat
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestOrderFromInquiry(t *testing.T) {
+	inq := &InquiryResponse{
+		ProductCode:  "bpjs-kesehatan",
+		ClientNumber: "0001234567890",
+		RefID:        "ref-1",
+		Fields:       Fields{{Name: "months", Value: "1"}},
+		MinPayment:   50000,
+		MaxPayment:   200000,
+	}
+
+	got, err := OrderFromInquiry(inq,
+		WithPayRequestID("order-1"),
+		WithPayAmount(100000),
+		WithPayFields(Field{Name: "months", Value: "2"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &OrderRequest{
+		RequestID:    "order-1",
+		ProductCode:  "bpjs-kesehatan",
+		ClientNumber: "0001234567890",
+		Amount:       100000,
+		Fields:       Fields{{Name: "months", Value: "2"}, {Name: REF_ID_FIELD, Value: "ref-1"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("OrderFromInquiry() got = %v, want %v", got, want)
+	}
+
+	if inq.Fields[0].Value != "1" {
+		t.Errorf("OrderFromInquiry() modified the inquiry fields")
+	}
+
+	_, err = OrderFromInquiry(inq, WithPayAmount(300000))
+	var verr *ValidationError
+	if !errors.As(err, &verr) || !verr.Has("amount") || !verr.Has("request_id") {
+		t.Errorf("OrderFromInquiry() error = %v, want amount and request_id", err)
+	}
+}
+
+func TestClient_PayInquiryExpired(t *testing.T) {
+	cln, err := NewClient("abc", "cde", PrivateKeyDummy,
+		WithLogger(log.New(io.Discard, "", 0)),
+		WithInquiryPaymentTTL(time.Minute),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inq := &InquiryResponse{ProductCode: "pln-postpaid", ClientNumber: "512345678901"}
+	_, err = cln.PayInquiry(context.Background(), inq, WithPayRequestID("order-1"))
+	if !errors.Is(err, ErrInquiryExpired) {
+		t.Errorf("PayInquiry() unknown inquiry error = %v, want %v", err, ErrInquiryExpired)
+	}
+
+	_, err = cln.PayInquiry(context.Background(), inq,
+		WithPayRequestID("order-1"),
+		WithInquiredAt(time.Now().Add(-2*time.Minute)),
+	)
+	if !errors.Is(err, ErrInquiryExpired) {
+		t.Errorf("PayInquiry() old inquiry error = %v, want %v", err, ErrInquiryExpired)
+	}
+}
+
+func TestClient_PayInquiryGeneratedRequestID(t *testing.T) {
+	oauthServer := newTestOauthServer()
+	defer oauthServer.Close()
+
+	satServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"errors":[{"status":"400","code":"P00","detail":"rejected"}]}`))
+	}))
+	defer satServer.Close()
+
+	cln, err := NewClient("abc", "cde", PrivateKeyDummy,
+		WithLogger(log.New(io.Discard, "", 0)),
+		WithHTTPClient(&http.Client{}),
+		WithAccessTokenURL(oauthServer.URL+"/token"),
+		WithSatBaseURL(satServer.URL),
+		WithRequestIDGenerator(NewULIDGenerator()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inq := &InquiryResponse{ProductCode: "pln-postpaid", ClientNumber: "512345678901"}
+	_, err = cln.PayInquiry(context.Background(), inq, WithInquiredAt(time.Now()))
+	var errR APIResponseError
+	if !errors.As(err, &errR) {
+		t.Errorf("PayInquiry() error = %v, want the order sent with the generated request id", err)
+	}
+}
+
+func TestClient_PayInquiryAPI(t *testing.T) {
+	cln, err := NewClient("abc", "cde", PrivateKeyDummy,
+		WithLogger(log.New(io.Discard, "", 0)),
+		WithInquiryPaymentTTL(time.Minute),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got *OrderRequest
+	api := &Decorator{
+		CheckoutFunc: func(ctx context.Context, req *OrderRequest) (*OrderDetail, error) {
+			got = req
+			return &OrderDetail{RequestID: req.RequestID}, nil
+		},
+	}
+
+	now := time.Now()
+	first := &InquiryResponse{ProductCode: "pln-postpaid", ClientNumber: "512345678901", RefID: "ref-1", SalesPrice: 100000}
+	second := &InquiryResponse{ProductCode: "pln-postpaid", ClientNumber: "512345678901", RefID: "ref-2", SalesPrice: 120000}
+	cln.inquiries.record(first, now.Add(-2*time.Minute), time.Hour)
+	cln.inquiries.record(second, now, time.Hour)
+
+	// the inquiry of another bill doesn't refresh the first one
+	_, err = cln.PayInquiry(context.Background(), first, WithPayRequestID("order-1"), WithPayAPI(api))
+	if !errors.Is(err, ErrInquiryExpired) {
+		t.Errorf("PayInquiry() first inquiry error = %v, want %v", err, ErrInquiryExpired)
+	}
+
+	order, err := cln.PayInquiry(context.Background(), second, WithPayRequestID("order-2"), WithPayAPI(api))
+	if err != nil || order.RequestID != "order-2" || got == nil || got.RequestID != "order-2" {
+		t.Errorf("PayInquiry() got = %v, %v, want the order checked out through the API", order, err)
+	}
+}
@@ -0,0 +1,164 @@
+package sat
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tokopedia/golang-sat/internal/pool"
+)
+
+// ErrRateLimited is returned by the fail fast rate limiter when the operation has no token available
+var ErrRateLimited = errors.New(RATE_LIMITED)
+
+// RateLimit contains the token bucket limit of an operation
+type RateLimit struct {
+	// Rate is the max call per second
+	Rate float64
+	// Burst is the max call at once after the operation is idle, default 1
+	Burst int
+}
+
+// RateLimiterOption contains field you can configure on the rate limiter
+type RateLimiterOption struct {
+	failFast bool
+}
+
+type RateLimiterOptionFunc func(*RateLimiterOption)
+
+// WithFailFast returns ErrRateLimited immediately instead of waiting for the token
+func WithFailFast(failFast bool) RateLimiterOptionFunc {
+	return func(o *RateLimiterOption) {
+		o.failFast = failFast
+	}
+}
+
+// RateLimiter limits the calls of every operation using token bucket. It is safe to be shared
+// by many goroutines and many clients, the clients sharing the limiter share the limits.
+// The rate is lowered using X-RateLimit-Remaining and X-RateLimit-Reset response headers,
+// and the operation is paused using Retry-After header of 429 response
+type RateLimiter struct {
+	opt RateLimiterOption
+
+	mu      sync.Mutex
+	buckets map[Operation]*pool.Bucket
+}
+
+// NewRateLimiter will return a new rate limiter, the operation without limit is not limited
+func NewRateLimiter(limits map[Operation]RateLimit, opts ...RateLimiterOptionFunc) *RateLimiter {
+	opt := RateLimiterOption{}
+	for _, option := range opts {
+		option(&opt)
+	}
+
+	r := &RateLimiter{
+		opt:     opt,
+		buckets: map[Operation]*pool.Bucket{},
+	}
+
+	for op, limit := range limits {
+		r.buckets[op] = pool.NewBucket(limit.Rate, limit.Burst)
+	}
+
+	return r
+}
+
+// SetLimit changes the limit of the operation
+func (r *RateLimiter) SetLimit(op Operation, limit RateLimit) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	bucket, ok := r.buckets[op]
+	if !ok {
+		r.buckets[op] = pool.NewBucket(limit.Rate, limit.Burst)
+		return
+	}
+
+	bucket.SetLimit(limit.Rate, limit.Burst)
+}
+
+// Wait blocks until the operation can be called or ctx is done,
+// the fail fast limiter returns ErrRateLimited instead of waiting
+func (r *RateLimiter) Wait(ctx context.Context, op Operation) error {
+	bucket := r.bucket(op, false)
+	if bucket == nil {
+		return ctx.Err()
+	}
+
+	if r.opt.failFast {
+		if !bucket.Allow() {
+			return ErrRateLimited
+		}
+
+		return nil
+	}
+
+	return bucket.Wait(ctx)
+}
+
+// observe adjusts the limit of the operation using the rate limit headers of the response
+func (r *RateLimiter) observe(op Operation, resp *http.Response) {
+	now := time.Now()
+	if resp.StatusCode == http.StatusTooManyRequests {
+		if retryAfter, ok := parseRetryAfter(resp.Header.Get(RETRY_AFTER_HEADER_KEY), now); ok {
+			r.bucket(op, true).Pause(now.Add(retryAfter))
+		}
+	}
+
+	remaining, err := strconv.ParseFloat(resp.Header.Get(RATE_LIMIT_REMAINING_HEADER_KEY), 64)
+	if err != nil {
+		return
+	}
+
+	reset, err := strconv.ParseFloat(resp.Header.Get(RATE_LIMIT_RESET_HEADER_KEY), 64)
+	if err != nil || reset <= 0 {
+		return
+	}
+
+	bucket := r.bucket(op, remaining <= 0)
+	if bucket == nil {
+		return
+	}
+
+	if remaining <= 0 {
+		bucket.Pause(now.Add(time.Duration(reset * float64(time.Second))))
+		return
+	}
+
+	bucket.Adjust(remaining / reset)
+}
+
+// bucket returns the bucket of the operation, the unlimited bucket is created when create is true
+func (r *RateLimiter) bucket(op Operation, create bool) *pool.Bucket {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	bucket, ok := r.buckets[op]
+	if !ok && create {
+		bucket = pool.NewBucket(0, 1)
+		r.buckets[op] = bucket
+	}
+
+	return bucket
+}
+
+// parseRetryAfter parses Retry-After header in seconds or http date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+
+	return at.Sub(now), true
+}
@@ -0,0 +1,107 @@
+package sat
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	ctx := context.Background()
+	limiter := NewRateLimiter(map[Operation]RateLimit{
+		OperationCheckout: {Rate: 1, Burst: 2},
+	}, WithFailFast(true))
+
+	for i := 0; i < 2; i++ {
+		if err := limiter.Wait(ctx, OperationCheckout); err != nil {
+			t.Fatalf("Wait() burst %d error = %v", i, err)
+		}
+	}
+
+	if err := limiter.Wait(ctx, OperationCheckout); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Wait() error = %v, want %v", err, ErrRateLimited)
+	}
+
+	// the operation without limit is not limited
+	for i := 0; i < 10; i++ {
+		if err := limiter.Wait(ctx, OperationInquiry); err != nil {
+			t.Fatalf("Wait() unlimited error = %v", err)
+		}
+	}
+
+	limiter.SetLimit(OperationCheckout, RateLimit{Rate: 1000})
+	time.Sleep(5 * time.Millisecond)
+	if err := limiter.Wait(ctx, OperationCheckout); err != nil {
+		t.Errorf("Wait() after SetLimit error = %v", err)
+	}
+}
+
+func TestRateLimiter_Wait(t *testing.T) {
+	limiter := NewRateLimiter(map[Operation]RateLimit{OperationCheckStatus: {Rate: 50}})
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := limiter.Wait(context.Background(), OperationCheckStatus); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Wait() 4 calls at 50/s took %v, want >= 50ms", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	limiter.bucket(OperationCheckStatus, false).Pause(time.Now().Add(time.Hour))
+	if err := limiter.Wait(ctx, OperationCheckStatus); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait() paused error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestClient_rateLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case PING_PATH:
+			w.Header().Set(RETRY_AFTER_HEADER_KEY, "3600")
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.Header().Set(RATE_LIMIT_REMAINING_HEADER_KEY, "5")
+			w.Header().Set(RATE_LIMIT_RESET_HEADER_KEY, "10")
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	limiter := NewRateLimiter(map[Operation]RateLimit{OperationAccount: {Rate: 100}}, WithFailFast(true))
+	cln := &Client{
+		http:        srv.Client(),
+		logger:      log.New(io.Discard, "", 0),
+		satBaseURL:  srv.URL,
+		rateLimiter: limiter,
+	}
+
+	_, err := cln.Ping(context.Background())
+	var errI *InternalError
+	if !errors.As(err, &errI) || errI.Response().StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("Ping() error = %v, want 429", err)
+	}
+
+	// the unlimited operation is paused by Retry-After
+	_, err = cln.Ping(context.Background())
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Ping() after 429 error = %v, want %v", err, ErrRateLimited)
+	}
+
+	cln.Account(context.Background())
+	if rate := limiter.bucket(OperationAccount, false).Rate(); rate != 0.5 {
+		t.Errorf("Account() adjusted rate = %v, want 0.5", rate)
+	}
+}
@@ -0,0 +1,210 @@
+// Package reconcile checks the local order records against SAT using CheckStatus,
+// and produces a report of matches, mismatches and unknown orders
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	sat "github.com/tokopedia/golang-sat"
+	"github.com/tokopedia/golang-sat/internal/jsonl"
+	"github.com/tokopedia/golang-sat/internal/pool"
+)
+
+// Kind is the result kind of the reconciliation
+type Kind string
+
+const (
+	// KindMatch is for order agree with the local record
+	KindMatch Kind = "MATCH"
+	// KindStatusMismatch is for order with different status
+	KindStatusMismatch Kind = "STATUS_MISMATCH"
+	// KindPriceMismatch is for order with the same status but different sales price, admin fee or partner fee
+	KindPriceMismatch Kind = "PRICE_MISMATCH"
+	// KindUnknown is for order not found on SAT
+	KindUnknown Kind = "UNKNOWN"
+	// KindError is for order failed to be checked, it is checked again when the reconciliation is resumed
+	KindError Kind = "ERROR"
+)
+
+// Record is the local order record
+type Record struct {
+	RequestID  string `json:"request_id"`
+	Status     string `json:"status"`
+	SalesPrice int64  `json:"sales_price"`
+	AdminFee   int64  `json:"admin_fee"`
+	PartnerFee int64  `json:"partner_fee"`
+}
+
+// Result contains the reconciliation result of a record
+type Result struct {
+	RequestID string           `json:"request_id"`
+	Kind      Kind             `json:"kind"`
+	Expected  Record           `json:"expected"`
+	Actual    *sat.OrderDetail `json:"actual,omitempty"`
+	// Fields contains the mismatched field names
+	Fields    []string  `json:"fields,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// StatusChecker fetches the order, sat.API and *sat.Client satisfy this interface
+type StatusChecker interface {
+	CheckStatus(ctx context.Context, requestID string, opts ...sat.CallOptionFunc) (*sat.OrderDetail, error)
+}
+
+// Options contains field you can configure on the reconciliation
+type Options struct {
+	// Concurrency is the max in-flight CheckStatus, default 4
+	Concurrency int
+	// RateLimit is the max CheckStatus per second, 0 is unlimited
+	RateLimit float64
+	// Checkpoint is the file path where every checked request id is appended,
+	// the request ids already on the checkpoint are not checked again when the reconciliation is resumed
+	Checkpoint string
+	// Logger is used to log the failed checkpoint write
+	Logger *log.Logger
+}
+
+// Run checks every record using CheckStatus, the identical request id is checked once.
+// When ctx is done the partial report is returned with ctx error, call Run again with the same checkpoint to resume
+func Run(ctx context.Context, checker StatusChecker, records []Record, opts Options) (*Report, error) {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 4
+	}
+
+	if opts.Logger == nil {
+		opts.Logger = log.New(log.Writer(), "[sat] ", 0)
+	}
+
+	done := map[string]*Result{}
+	var checkpoint *jsonl.File
+	if opts.Checkpoint != "" {
+		err := jsonl.Load(opts.Checkpoint, func() interface{} { return new(Result) }, func(v interface{}) {
+			result := v.(*Result)
+			done[result.RequestID] = result
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		checkpoint, err = jsonl.Open(opts.Checkpoint)
+		if err != nil {
+			return nil, err
+		}
+		defer checkpoint.Close()
+	}
+
+	var (
+		pending []string
+		mu      sync.Mutex
+	)
+	for _, record := range records {
+		if _, ok := done[record.RequestID]; ok {
+			continue
+		}
+
+		done[record.RequestID] = nil
+		pending = append(pending, record.RequestID)
+	}
+
+	limiter := pool.NewBucket(opts.RateLimit, 1)
+	pool.Run(ctx, pool.Indexes(len(pending)), opts.Concurrency, limiter, func(i int) {
+		result := check(ctx, checker, pending[i])
+		if ctx.Err() != nil && result.Kind == KindError {
+			return
+		}
+
+		mu.Lock()
+		done[result.RequestID] = result
+		mu.Unlock()
+
+		if checkpoint == nil || result.Kind == KindError {
+			return
+		}
+
+		err := checkpoint.Append(result)
+		if err != nil {
+			opts.Logger.Println(err)
+		}
+	})
+
+	report := &Report{}
+	for _, record := range records {
+		if result := done[record.RequestID]; result != nil {
+			report.Results = append(report.Results, compare(record, result))
+		}
+	}
+
+	return report, ctx.Err()
+}
+
+// check fetches the order of the request id, the result kind is set only when the order is not found or failed to be checked
+func check(ctx context.Context, checker StatusChecker, requestID string) *Result {
+	result := &Result{RequestID: requestID}
+	order, err := checker.CheckStatus(ctx, requestID)
+	result.CheckedAt = time.Now()
+
+	var errR *sat.ErrorResponse
+	switch {
+	case errors.As(err, &errR) && errR.Status() == strconv.Itoa(http.StatusNotFound):
+		result.Kind = KindUnknown
+		result.Error = errR.Error()
+	case err != nil:
+		result.Kind = KindError
+		result.Error = err.Error()
+	default:
+		result.Actual = order
+	}
+
+	return result
+}
+
+// compare will return the result of the record using the fetched order of its request id
+func compare(record Record, checked *Result) *Result {
+	result := &Result{
+		RequestID: record.RequestID,
+		Kind:      checked.Kind,
+		Expected:  record,
+		Actual:    checked.Actual,
+		Error:     checked.Error,
+		CheckedAt: checked.CheckedAt,
+	}
+
+	order := checked.Actual
+	if order == nil {
+		return result
+	}
+
+	if order.Status != record.Status {
+		result.Fields = append(result.Fields, "status")
+	}
+
+	if order.SalesPrice != record.SalesPrice {
+		result.Fields = append(result.Fields, "sales_price")
+	}
+
+	if order.AdminFee != record.AdminFee {
+		result.Fields = append(result.Fields, "admin_fee")
+	}
+
+	if order.PartnerFee != record.PartnerFee {
+		result.Fields = append(result.Fields, "partner_fee")
+	}
+
+	switch {
+	case len(result.Fields) == 0:
+		result.Kind = KindMatch
+	case result.Fields[0] == "status":
+		result.Kind = KindStatusMismatch
+	default:
+		result.Kind = KindPriceMismatch
+	}
+
+	return result
+}
@@ -0,0 +1,183 @@
+package reconcile
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	sat "github.com/tokopedia/golang-sat"
+)
+
+type stubChecker struct {
+	mu     sync.Mutex
+	calls  map[string]int
+	orders map[string]*sat.OrderDetail
+	err    map[string]error
+}
+
+func (s *stubChecker) CheckStatus(ctx context.Context, requestID string, opts ...sat.CallOptionFunc) (*sat.OrderDetail, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls[requestID]++
+	if err, ok := s.err[requestID]; ok {
+		return nil, err
+	}
+
+	if order, ok := s.orders[requestID]; ok {
+		return order, nil
+	}
+
+	return nil, &sat.ErrorResponse{Errors: []*sat.ErrorObject{{Status: "404", Code: "O01", Detail: "order is not found"}}}
+}
+
+func TestRun(t *testing.T) {
+	checker := &stubChecker{
+		calls: map[string]int{},
+		orders: map[string]*sat.OrderDetail{
+			"order-1": {RequestID: "order-1", Status: sat.OrderStatusSuccess, SalesPrice: 10500, AdminFee: 0, PartnerFee: 100},
+			"order-2": {RequestID: "order-2", Status: sat.OrderStatusFailed, SalesPrice: 10500},
+			"order-3": {RequestID: "order-3", Status: sat.OrderStatusSuccess, SalesPrice: 10500, AdminFee: 2500},
+		},
+		err: map[string]error{"order-5": errors.New("connection reset")},
+	}
+
+	records := []Record{
+		{RequestID: "order-1", Status: sat.OrderStatusSuccess, SalesPrice: 10500, PartnerFee: 100},
+		{RequestID: "order-2", Status: sat.OrderStatusSuccess, SalesPrice: 10500},
+		{RequestID: "order-3", Status: sat.OrderStatusSuccess, SalesPrice: 10500, AdminFee: 2000},
+		{RequestID: "order-4", Status: sat.OrderStatusPending},
+		{RequestID: "order-5", Status: sat.OrderStatusPending},
+	}
+
+	checkpoint := filepath.Join(t.TempDir(), "checkpoint.jsonl")
+	opts := Options{Concurrency: 2, RateLimit: 1000, Checkpoint: checkpoint, Logger: log.New(io.Discard, "", 0)}
+	report, err := Run(context.Background(), checker, records, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Kind{KindMatch, KindStatusMismatch, KindPriceMismatch, KindUnknown, KindError}
+	if len(report.Results) != len(want) {
+		t.Fatalf("Run() got %d results, want %d", len(report.Results), len(want))
+	}
+
+	for i, kind := range want {
+		if report.Results[i].Kind != kind {
+			t.Errorf("Run() result %s got = %s, want %s", records[i].RequestID, report.Results[i].Kind, kind)
+		}
+	}
+
+	if fields := report.Results[2].Fields; len(fields) != 1 || fields[0] != "admin_fee" {
+		t.Errorf("Run() mismatched fields got = %v, want [admin_fee]", fields)
+	}
+
+	if summary := report.Summary(); summary[KindMatch] != 1 || summary[KindError] != 1 {
+		t.Errorf("Summary() got = %v", summary)
+	}
+
+	// resume checks only the failed record
+	delete(checker.err, "order-5")
+	report, err = Run(context.Background(), checker, records, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for id, calls := range checker.calls {
+		want := 1
+		if id == "order-5" {
+			want = 2
+		}
+
+		if calls != want {
+			t.Errorf("CheckStatus(%s) called %d times, want %d", id, calls, want)
+		}
+	}
+
+	if report.Results[4].Kind != KindUnknown {
+		t.Errorf("Run() resumed result got = %s, want %s", report.Results[4].Kind, KindUnknown)
+	}
+
+	var buf bytes.Buffer
+	err = report.WriteCSV(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil || len(rows) != len(records)+1 || rows[2][3] != sat.OrderStatusSuccess || rows[2][4] != sat.OrderStatusFailed {
+		t.Errorf("WriteCSV() got = %v, %v", rows, err)
+	}
+
+	buf.Reset()
+	err = report.WriteJSON(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := new(Report)
+	if err := json.Unmarshal(buf.Bytes(), decoded); err != nil || len(decoded.Results) != len(records) {
+		t.Errorf("WriteJSON() got = %v, %v", decoded, err)
+	}
+}
+
+func TestRun_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	checker := &stubChecker{calls: map[string]int{}}
+	report, err := Run(ctx, checker, []Record{{RequestID: "order-1"}}, Options{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() error = %v, want %v", err, context.Canceled)
+	}
+
+	if report == nil || len(report.Results) != 0 {
+		t.Errorf("Run() got = %v, want empty report", report)
+	}
+}
+
+func TestRun_Duplicate(t *testing.T) {
+	checker := &stubChecker{
+		calls: map[string]int{},
+		orders: map[string]*sat.OrderDetail{
+			"order-1": {RequestID: "order-1", Status: sat.OrderStatusSuccess, SalesPrice: 10500},
+		},
+	}
+
+	records := []Record{
+		{RequestID: "order-1", Status: sat.OrderStatusSuccess, SalesPrice: 10500},
+		{RequestID: "order-1", Status: sat.OrderStatusPending, SalesPrice: 10500},
+		{RequestID: "order-1", Status: sat.OrderStatusSuccess, SalesPrice: 11000},
+	}
+
+	checkpoint := filepath.Join(t.TempDir(), "checkpoint.jsonl")
+	opts := Options{Concurrency: 2, Checkpoint: checkpoint, Logger: log.New(io.Discard, "", 0)}
+	want := []Kind{KindMatch, KindStatusMismatch, KindPriceMismatch}
+	for run := 0; run < 2; run++ {
+		report, err := Run(context.Background(), checker, records, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(report.Results) != len(want) {
+			t.Fatalf("Run() got %d results, want %d", len(report.Results), len(want))
+		}
+
+		for i, kind := range want {
+			if report.Results[i].Kind != kind {
+				t.Errorf("Run() result %d got = %s, want %s", i, report.Results[i].Kind, kind)
+			}
+		}
+	}
+
+	if calls := checker.calls["order-1"]; calls != 1 {
+		t.Errorf("CheckStatus(order-1) called %d times, want 1", calls)
+	}
+}
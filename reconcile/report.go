@@ -0,0 +1,89 @@
+package reconcile
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Report contains the reconciliation result of every record in the order of the records
+type Report struct {
+	Results []*Result `json:"results"`
+}
+
+// Summary will return the number of results of every kind
+func (r *Report) Summary() map[Kind]int {
+	summary := map[Kind]int{}
+	for _, result := range r.Results {
+		summary[result.Kind]++
+	}
+
+	return summary
+}
+
+// Filter will return the results of the kinds
+func (r *Report) Filter(kinds ...Kind) []*Result {
+	var results []*Result
+	for _, result := range r.Results {
+		for _, kind := range kinds {
+			if result.Kind == kind {
+				results = append(results, result)
+				break
+			}
+		}
+	}
+
+	return results
+}
+
+// WriteJSON writes the report as json
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteCSV writes the report as csv with header, one row for every result
+func (r *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write([]string{
+		"request_id", "kind", "mismatched_fields",
+		"expected_status", "actual_status",
+		"expected_sales_price", "actual_sales_price",
+		"expected_admin_fee", "actual_admin_fee",
+		"expected_partner_fee", "actual_partner_fee",
+		"error", "checked_at",
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, result := range r.Results {
+		row := []string{
+			result.RequestID, string(result.Kind), strings.Join(result.Fields, "|"),
+			result.Expected.Status, "",
+			strconv.FormatInt(result.Expected.SalesPrice, 10), "",
+			strconv.FormatInt(result.Expected.AdminFee, 10), "",
+			strconv.FormatInt(result.Expected.PartnerFee, 10), "",
+			result.Error, result.CheckedAt.Format(time.RFC3339),
+		}
+
+		if result.Actual != nil {
+			row[4] = result.Actual.Status
+			row[6] = strconv.FormatInt(result.Actual.SalesPrice, 10)
+			row[8] = strconv.FormatInt(result.Actual.AdminFee, 10)
+			row[10] = strconv.FormatInt(result.Actual.PartnerFee, 10)
+		}
+
+		err = cw.Write(row)
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
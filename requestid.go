@@ -0,0 +1,227 @@
+package sat
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// ErrDuplicateRequestID is returned by Checkout when the request id is already submitted, only when the duplicate guard is enabled
+var ErrDuplicateRequestID = errors.New(DUPLICATE_REQUEST_ID)
+
+var defaultRequestIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.:-]+$`)
+
+// RequestIDGenerator generates the request id of the order
+type RequestIDGenerator interface {
+	Generate() string
+}
+
+// RequestIDGeneratorFunc is an adapter to use a function as RequestIDGenerator
+type RequestIDGeneratorFunc func() string
+
+// Generate calls f()
+func (f RequestIDGeneratorFunc) Generate() string {
+	return f()
+}
+
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewULIDGenerator will return a generator of ULID, 26 characters sortable by the creation time.
+// The ids generated within the same millisecond are monotonic
+func NewULIDGenerator() RequestIDGenerator {
+	var (
+		mu      sync.Mutex
+		lastMs  uint64
+		entropy [10]byte
+	)
+
+	return RequestIDGeneratorFunc(func() string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		ms := uint64(time.Now().UnixMilli())
+		if ms > lastMs {
+			lastMs = ms
+			rand.Read(entropy[:])
+		} else {
+			// increment the entropy to keep the order within the same millisecond
+			for i := len(entropy) - 1; i >= 0; i-- {
+				entropy[i]++
+				if entropy[i] != 0 {
+					break
+				}
+			}
+		}
+
+		var id [16]byte
+		id[0] = byte(lastMs >> 40)
+		id[1] = byte(lastMs >> 32)
+		id[2] = byte(lastMs >> 24)
+		id[3] = byte(lastMs >> 16)
+		id[4] = byte(lastMs >> 8)
+		id[5] = byte(lastMs)
+		copy(id[6:], entropy[:])
+
+		return encodeCrockford(id)
+	})
+}
+
+// encodeCrockford encodes the 128 bits into 26 characters of Crockford base32
+func encodeCrockford(id [16]byte) string {
+	hi := binary.BigEndian.Uint64(id[:8])
+	lo := binary.BigEndian.Uint64(id[8:])
+
+	out := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		out[i] = crockfordAlphabet[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+
+	return string(out)
+}
+
+// NewUUIDv7Generator will return a generator of UUID version 7, sortable by the creation time in millisecond
+func NewUUIDv7Generator() RequestIDGenerator {
+	return RequestIDGeneratorFunc(func() string {
+		var id [16]byte
+		rand.Read(id[6:])
+
+		ms := uint64(time.Now().UnixMilli())
+		id[0] = byte(ms >> 40)
+		id[1] = byte(ms >> 32)
+		id[2] = byte(ms >> 24)
+		id[3] = byte(ms >> 16)
+		id[4] = byte(ms >> 8)
+		id[5] = byte(ms)
+		id[6] = id[6]&0x0f | 0x70
+		id[8] = id[8]&0x3f | 0x80
+
+		h := hex.EncodeToString(id[:])
+		return fmt.Sprintf("%s-%s-%s-%s-%s", h[0:8], h[8:12], h[12:16], h[16:20], h[20:32])
+	})
+}
+
+// NewPrefixGenerator will return a generator of prefix, UTC timestamp in millisecond and random suffix,
+// example INV-20240131150405123-9f86d081a3c2
+func NewPrefixGenerator(prefix string) RequestIDGenerator {
+	return RequestIDGeneratorFunc(func() string {
+		var suffix [6]byte
+		rand.Read(suffix[:])
+
+		now := time.Now().UTC()
+		return fmt.Sprintf("%s-%s%03d-%s", prefix, now.Format("20060102150405"), now.Nanosecond()/int(time.Millisecond), hex.EncodeToString(suffix[:]))
+	})
+}
+
+// EnsureRequestID will return a Middleware filling the empty RequestID of Checkout using the generator.
+// Put it as the outermost middleware, so the other middlewares example the ledger and the journal
+// see the request id generated before the checkout
+func EnsureRequestID(generator RequestIDGenerator) Middleware {
+	return func(next API) API {
+		return &Decorator{
+			Next: next,
+			CheckoutFunc: func(ctx context.Context, req *OrderRequest) (*OrderDetail, error) {
+				if req.RequestID == "" {
+					req.RequestID = generator.Generate()
+				}
+
+				return next.Checkout(ctx, req)
+			},
+		}
+	}
+}
+
+// checkRequestID returns the reason when the request id breaks the length and charset rules, empty when it is valid
+func checkRequestID(requestID string, maxLength int, pattern *regexp.Regexp) string {
+	switch {
+	case requestID == "":
+		return "can't be empty"
+	case maxLength > 0 && len(requestID) > maxLength:
+		return fmt.Sprintf("can't be longer than %d characters", maxLength)
+	case pattern != nil && !pattern.MatchString(requestID):
+		return "contains invalid characters"
+	}
+
+	return ""
+}
+
+// requestIDGuard remembers the recent submitted request ids to reject the local duplicate
+type requestIDGuard struct {
+	mu    sync.Mutex
+	size  int
+	seen  map[string]struct{}
+	order []string
+}
+
+func newRequestIDGuard(size int) *requestIDGuard {
+	return &requestIDGuard{
+		size: size,
+		seen: make(map[string]struct{}, size),
+	}
+}
+
+// add returns false when the request id is already added
+func (g *requestIDGuard) add(requestID string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.seen[requestID]; ok {
+		return false
+	}
+
+	if len(g.order) >= g.size {
+		delete(g.seen, g.order[0])
+		g.order = g.order[1:]
+	}
+
+	g.seen[requestID] = struct{}{}
+	g.order = append(g.order, requestID)
+	return true
+}
+
+// remove forgets the request id, so the failed checkout can be retried using the same request id
+func (g *requestIDGuard) remove(requestID string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.seen[requestID]; !ok {
+		return
+	}
+
+	delete(g.seen, requestID)
+	for i, id := range g.order {
+		if id == requestID {
+			g.order = append(g.order[:i], g.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// prepareRequestID fills the empty request id using the generator, validates the request id
+// and rejects the duplicate when the guard is enabled
+func (c *Client) prepareRequestID(req *OrderRequest) error {
+	if c.requestIDGenerator != nil {
+		if req.RequestID == "" {
+			req.RequestID = c.requestIDGenerator.Generate()
+		}
+
+		if reason := checkRequestID(req.RequestID, DEFAULT_REQUEST_ID_MAX_LENGTH, defaultRequestIDPattern); reason != "" {
+			verr := &ValidationError{}
+			verr.add("request_id", reason)
+			return verr
+		}
+	}
+
+	if c.requestIDGuard != nil && !c.requestIDGuard.add(req.RequestID) {
+		return ErrDuplicateRequestID
+	}
+
+	return nil
+}
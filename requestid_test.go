@@ -0,0 +1,204 @@
+package sat
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"testing"
+
+	"github.com/google/jsonapi"
+	"github.com/tokopedia/golang-sat/signature"
+)
+
+func TestRequestIDGenerator(t *testing.T) {
+	tests := []struct {
+		name      string
+		generator RequestIDGenerator
+		pattern   *regexp.Regexp
+		sortable  bool
+	}{
+		{"ulid", NewULIDGenerator(), regexp.MustCompile(`^[0-9A-HJKMNP-TV-Z]{26}$`), true},
+		{"uuidv7", NewUUIDv7Generator(), regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), false},
+		{"prefix", NewPrefixGenerator("INV"), regexp.MustCompile(`^INV-\d{17}-[0-9a-f]{12}$`), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids := make([]string, 1000)
+			seen := map[string]bool{}
+			for i := range ids {
+				ids[i] = tt.generator.Generate()
+				if !tt.pattern.MatchString(ids[i]) || checkRequestID(ids[i], DEFAULT_REQUEST_ID_MAX_LENGTH, defaultRequestIDPattern) != "" {
+					t.Fatalf("Generate() got = %s, invalid format", ids[i])
+				}
+
+				if seen[ids[i]] {
+					t.Fatalf("Generate() got = %s, duplicate", ids[i])
+				}
+				seen[ids[i]] = true
+			}
+
+			if tt.sortable && !sort.StringsAreSorted(ids) {
+				t.Errorf("Generate() ids are not sorted")
+			}
+		})
+	}
+}
+
+func TestClient_CheckoutRequestID(t *testing.T) {
+	ctx := context.Background()
+	oauthServer := newTestOauthServer()
+	defer oauthServer.Close()
+
+	checkouts := 0
+	satServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		checkouts++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"errors":[{"status":"400","code":"P00","detail":"rejected"}]}`))
+	}))
+	defer satServer.Close()
+
+	cln, err := NewClient("abc", "cde", PrivateKeyDummy,
+		WithLogger(log.New(io.Discard, "", 0)),
+		WithHTTPClient(&http.Client{}),
+		WithAccessTokenURL(oauthServer.URL+"/token"),
+		WithSatBaseURL(satServer.URL),
+		WithRequestIDGenerator(NewPrefixGenerator("INV")),
+		WithDuplicateGuard(10),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := &OrderRequest{ProductCode: "telkomsel-10k", ClientNumber: "081234567890"}
+	_, err = cln.Checkout(ctx, req)
+	var errR APIResponseError
+	if !errors.As(err, &errR) || req.RequestID == "" {
+		t.Fatalf("Checkout() error = %v, request id = %q, want generated request id", err, req.RequestID)
+	}
+
+	// the failed checkout can be retried using the same request id
+	_, err = cln.Checkout(ctx, req)
+	if !errors.As(err, &errR) {
+		t.Errorf("Checkout() retry error = %v", err)
+	}
+
+	_, err = cln.Checkout(ctx, &OrderRequest{RequestID: "order 1!", ProductCode: "telkomsel-10k"})
+	var verr *ValidationError
+	if !errors.As(err, &verr) || !verr.Has("request_id") {
+		t.Errorf("Checkout() error = %v, want invalid request id", err)
+	}
+
+	if checkouts != 2 {
+		t.Errorf("Checkout() sent = %d, want 2", checkouts)
+	}
+
+	guard := newRequestIDGuard(2)
+	if !guard.add("a") || guard.add("a") || !guard.add("b") || !guard.add("c") || !guard.add("a") {
+		t.Errorf("requestIDGuard rejects the wrong request id")
+	}
+}
+
+func TestEnsureRequestID(t *testing.T) {
+	ctx := context.Background()
+	oauthServer := newTestOauthServer()
+	defer oauthServer.Close()
+
+	sgn := signature.Init(signature.Options{
+		PrivateKeyString: PrivateKeyDummy,
+		PublicKeyString:  PublicKeyDummy,
+	})
+
+	satServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		order := new(OrderRequest)
+		if err := jsonapi.UnmarshalPayload(req.Body, order); err != nil || order.RequestID == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		b := &bytes.Buffer{}
+		jsonapi.MarshalPayload(b, &OrderDetail{RequestID: order.RequestID, ProductCode: order.ProductCode, Status: OrderStatusPending})
+		signt, _ := sgn.Sign(b.Bytes())
+		w.Header().Set(SIGNATURE_HEADER_KEY, signt)
+		w.Write(b.Bytes())
+	}))
+	defer satServer.Close()
+
+	logger := log.New(io.Discard, "", 0)
+	cln, err := NewClient("abc", "cde", PrivateKeyDummy,
+		WithLogger(logger),
+		WithServerPublicKeyString(PublicKeyDummy),
+		WithHTTPClient(&http.Client{}),
+		WithAccessTokenURL(oauthServer.URL+"/token"),
+		WithSatBaseURL(satServer.URL),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	catalog := NewProductCatalog(&Decorator{
+		ListProductFunc: func(ctx context.Context, code string) ([]*Product, error) {
+			return []*Product{{Code: "telkomsel-10k", SalesPrice: 10200, Status: ProductStatusActive}}, nil
+		},
+	}, WithCatalogLogger(logger))
+	catalog.Refresh(ctx)
+
+	ledger := NewLedger(cln, WithLedgerLogger(logger), WithLedgerProducts(catalog))
+	store, err := NewFileJournalStore(filepath.Join(t.TempDir(), "journal.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	journal := NewOrderJournal(cln, store, WithJournalLogger(logger))
+
+	api := Chain(cln,
+		EnsureRequestID(NewULIDGenerator()),
+		NewValidator(catalog).Middleware(),
+		ledger.Middleware(),
+		journal.Middleware(),
+	)
+
+	ids := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		req := &OrderRequest{ProductCode: "telkomsel-10k", ClientNumber: "081234567890"}
+		order, err := api.Checkout(ctx, req)
+		if err != nil {
+			t.Fatalf("Checkout() error = %v", err)
+		}
+
+		if req.RequestID == "" || order.RequestID != req.RequestID {
+			t.Errorf("Checkout() request id got = %q, order %q", req.RequestID, order.RequestID)
+		}
+
+		ids[req.RequestID] = true
+	}
+
+	if len(ids) != 3 || len(ledger.Reservations()) != 3 {
+		t.Errorf("Reservations() got = %d, want one per generated request id", len(ledger.Reservations()))
+	}
+
+	entries, err := store.Unresolved(ctx)
+	if err != nil || len(entries) != 3 {
+		t.Errorf("Unresolved() got = %d, %v, want one entry per generated request id", len(entries), err)
+	}
+
+	for _, entry := range entries {
+		if !ids[entry.RequestID] {
+			t.Errorf("Unresolved() got unknown request id %q", entry.RequestID)
+		}
+	}
+
+	// without EnsureRequestID the journal refuses the order instead of filing it under the empty request id
+	_, err = Chain(cln, journal.Middleware()).Checkout(ctx, &OrderRequest{ProductCode: "telkomsel-10k", ClientNumber: "081234567890"})
+	var verr *ValidationError
+	if !errors.As(err, &verr) || !verr.Has("request_id") {
+		t.Errorf("Checkout() error = %v, want empty request id", err)
+	}
+}
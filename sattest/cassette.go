@@ -0,0 +1,443 @@
+package sattest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// REDACTED replaces every secret recorded on the cassette
+const REDACTED = "REDACTED"
+
+// Interaction is a single http exchange recorded as one line of the cassette
+type Interaction struct {
+	Request    RecordedRequest  `json:"request"`
+	Response   RecordedResponse `json:"response"`
+	RecordedAt time.Time        `json:"recorded_at"`
+	Duration   time.Duration    `json:"duration"`
+}
+
+// RecordedRequest contains the recorded http request
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body"`
+}
+
+// RecordedResponse contains the recorded http response
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+}
+
+// Redaction contains the secrets removed from the cassette at record time
+type Redaction struct {
+	// Headers are redacted from both request and response, the default is Authorization, Cookie and Set-Cookie
+	Headers []string
+	// Fields are redacted from JSON and form bodies, the default is access_token, refresh_token, client_id and client_secret
+	Fields []string
+}
+
+var defaultRedaction = Redaction{
+	Headers: []string{"Authorization", "Cookie", "Set-Cookie"},
+	Fields:  []string{"access_token", "refresh_token", "client_id", "client_secret"},
+}
+
+// RecorderOptions are needs to init the recorder
+type RecorderOptions struct {
+	// Base is the base RoundTripper used to make the real HTTP requests.
+	// If nil, http.DefaultTransport is used.
+	Base      http.RoundTripper
+	Redaction *Redaction
+}
+
+// Recorder is an http.RoundTripper that records every exchange into a JSON Lines cassette
+type Recorder struct {
+	base      http.RoundTripper
+	redaction Redaction
+	mu        sync.Mutex
+	file      *os.File
+}
+
+// NewRecorder will create the cassette file and return a new recorder,
+// use it as the Transport of the http client passed to sat.WithHTTPClient
+func NewRecorder(path string, opts RecorderOptions) (*Recorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Recorder{
+		base:      opts.Base,
+		redaction: defaultRedaction,
+		file:      file,
+	}
+
+	if r.base == nil {
+		r.base = http.DefaultTransport
+	}
+
+	if opts.Redaction != nil {
+		r.redaction = *opts.Redaction
+	}
+
+	return r, nil
+}
+
+// RoundTrip sends the request using the base RoundTripper and records the exchange
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := drainBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := r.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := drainBody(&resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	interaction := Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: r.redaction.header(req.Header),
+			Body:   r.redaction.body(reqBody),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     r.redaction.header(resp.Header),
+			Body:       r.redaction.body(respBody),
+		},
+		RecordedAt: start,
+		Duration:   time.Since(start),
+	}
+
+	line, err := json.Marshal(&interaction)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, err = r.file.Write(append(line, '\n'))
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Close closes the cassette file
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.file.Close()
+}
+
+// ReplayerOptions are needs to init the replayer
+type ReplayerOptions struct {
+	// Strict fails every request which is not recorded on the cassette,
+	// otherwise the request is sent using Base
+	Strict bool
+	// Base is the base RoundTripper used to send the unmatched requests.
+	// If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+	// RealTiming holds every response as long as the recorded duration
+	RealTiming bool
+	// Redaction must be the same redaction used by the recorder
+	Redaction *Redaction
+}
+
+// Replayer is an http.RoundTripper that responds every request using the recorded cassette.
+// Request is matched on method, path, query and normalized body, the interactions are replayed in the recorded order
+type Replayer struct {
+	opts         ReplayerOptions
+	redaction    Redaction
+	mu           sync.Mutex
+	interactions []*replayInteraction
+}
+
+type replayInteraction struct {
+	Interaction
+	key  string
+	used bool
+}
+
+// ErrUnmatchedRequest is returned by strict replayer when the request is not recorded on the cassette
+var ErrUnmatchedRequest = errors.New("sattest: request is not recorded on the cassette")
+
+// LoadCassette will read every interaction recorded on the cassette
+func LoadCassette(path string) ([]Interaction, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var interactions []Interaction
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var interaction Interaction
+		err = json.Unmarshal(line, &interaction)
+		if err != nil {
+			return nil, err
+		}
+
+		interactions = append(interactions, interaction)
+	}
+
+	return interactions, scanner.Err()
+}
+
+// NewReplayer will load the cassette and return a new replayer,
+// use it as the Transport of the http client passed to sat.WithHTTPClient
+func NewReplayer(path string, opts ReplayerOptions) (*Replayer, error) {
+	interactions, err := LoadCassette(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Replayer{
+		opts:      opts,
+		redaction: defaultRedaction,
+	}
+
+	if r.opts.Base == nil {
+		r.opts.Base = http.DefaultTransport
+	}
+
+	if opts.Redaction != nil {
+		r.redaction = *opts.Redaction
+	}
+
+	for _, interaction := range interactions {
+		u, err := url.Parse(interaction.Request.URL)
+		if err != nil {
+			return nil, err
+		}
+
+		r.interactions = append(r.interactions, &replayInteraction{
+			Interaction: interaction,
+			key:         matchKey(interaction.Request.Method, u, interaction.Request.Body),
+		})
+	}
+
+	return r, nil
+}
+
+// RoundTrip responds the request using the first unused interaction matched,
+// the last matched interaction is reused when every matched interaction is already used
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := drainBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	key := matchKey(req.Method, req.URL, r.redaction.body(body))
+
+	r.mu.Lock()
+	var found *replayInteraction
+	for _, interaction := range r.interactions {
+		if interaction.key != key {
+			continue
+		}
+
+		found = interaction
+		if !interaction.used {
+			break
+		}
+	}
+
+	if found != nil {
+		found.used = true
+	}
+	r.mu.Unlock()
+
+	if found == nil {
+		if r.opts.Strict {
+			return nil, fmt.Errorf("%w: %s %s", ErrUnmatchedRequest, req.Method, req.URL)
+		}
+
+		return r.opts.Base.RoundTrip(req)
+	}
+
+	if r.opts.RealTiming {
+		sleep(req.Context(), found.Duration)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", found.Response.StatusCode, http.StatusText(found.Response.StatusCode)),
+		StatusCode:    found.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        found.Response.Header.Clone(),
+		Body:          io.NopCloser(strings.NewReader(found.Response.Body)),
+		ContentLength: int64(len(found.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// Unused returns every interaction which is never replayed
+func (r *Replayer) Unused() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var unused []Interaction
+	for _, interaction := range r.interactions {
+		if !interaction.used {
+			unused = append(unused, interaction.Interaction)
+		}
+	}
+
+	return unused
+}
+
+// drainBody reads the body and replaces it so it can be read again
+func drainBody(body *io.ReadCloser) (string, error) {
+	if *body == nil || *body == http.NoBody {
+		return "", nil
+	}
+
+	b, err := io.ReadAll(*body)
+	if err != nil {
+		return "", err
+	}
+
+	(*body).Close()
+	*body = io.NopCloser(bytes.NewReader(b))
+	return string(b), nil
+}
+
+// matchKey returns the key used to match the request, the query and the JSON body are normalized
+func matchKey(method string, u *url.URL, body string) string {
+	return method + " " + u.Path + "?" + u.Query().Encode() + " " + normalizeBody(body)
+}
+
+// normalizeBody re-encodes the JSON body with sorted keys and no whitespace
+func normalizeBody(body string) string {
+	var v interface{}
+	err := json.Unmarshal([]byte(body), &v)
+	if err != nil {
+		return body
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+
+	return string(b)
+}
+
+// header returns a copy of the header with the secrets redacted
+func (r Redaction) header(h http.Header) http.Header {
+	h = h.Clone()
+	for _, key := range r.Headers {
+		if h.Get(key) != "" {
+			h.Set(key, REDACTED)
+		}
+	}
+
+	return h
+}
+
+// body returns the body with the secrets on JSON or form fields redacted,
+// the body is kept as it is when there is nothing to redact so the signature is still valid
+func (r Redaction) body(body string) string {
+	if body == "" || len(r.Fields) == 0 {
+		return body
+	}
+
+	var v interface{}
+	err := json.Unmarshal([]byte(body), &v)
+	if err == nil {
+		if !r.value(v) {
+			return body
+		}
+
+		b, err := json.Marshal(v)
+		if err != nil {
+			return body
+		}
+
+		return string(b)
+	}
+
+	form, err := url.ParseQuery(body)
+	if err != nil || len(form) == 0 {
+		return body
+	}
+
+	redacted := false
+	for _, field := range r.Fields {
+		if _, ok := form[field]; ok {
+			form.Set(field, REDACTED)
+			redacted = true
+		}
+	}
+
+	if !redacted {
+		return body
+	}
+
+	return form.Encode()
+}
+
+// value redacts every JSON object field recursively, it returns true when any field is redacted
+func (r Redaction) value(v interface{}) bool {
+	redacted := false
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, child := range val {
+			if r.isField(k) {
+				val[k] = REDACTED
+				redacted = true
+				continue
+			}
+
+			redacted = r.value(child) || redacted
+		}
+	case []interface{}:
+		for _, child := range val {
+			redacted = r.value(child) || redacted
+		}
+	}
+
+	return redacted
+}
+
+func (r Redaction) isField(name string) bool {
+	for _, field := range r.Fields {
+		if strings.EqualFold(field, name) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,95 @@
+package sattest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sat "github.com/tokopedia/golang-sat"
+)
+
+func TestRecorderReplayer(t *testing.T) {
+	ctx := context.Background()
+	cassette := filepath.Join(t.TempDir(), "cassette.jsonl")
+	order := &sat.OrderRequest{
+		RequestID:    "order-1",
+		ProductCode:  "telkomsel-10k",
+		ClientNumber: "081234567890",
+	}
+
+	srv := NewServer(WithTransition(StayPending))
+	recorder, err := NewRecorder(cassette, RecorderOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cln, err := srv.NewClient(sat.WithHTTPClient(&http.Client{Transport: recorder}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = cln.Checkout(ctx, order)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := cln.CheckStatus(ctx, order.RequestID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder.Close()
+	baseURL, tokenURL := srv.URL(), srv.TokenURL()
+	srv.Close()
+
+	b, err := os.ReadFile(cassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(b), ACCESS_TOKEN) {
+		t.Errorf("cassette contains the access token")
+	}
+
+	// the token request is replayed too, nothing is listening anymore
+	replayer, err := NewReplayer(cassette, ReplayerOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cln, err = sat.NewClient(
+		"abc",
+		"cde",
+		CLIENT_PRIVATE_KEY,
+		sat.WithServerPublicKeyString(SERVER_PUBLIC_KEY),
+		sat.WithHTTPClient(&http.Client{Transport: replayer}),
+		sat.WithSatBaseURL(baseURL),
+		sat.WithAccessTokenURL(tokenURL),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = cln.Checkout(ctx, order)
+	if err != nil {
+		t.Errorf("Checkout() error = %v", err)
+	}
+
+	got, err := cln.CheckStatus(ctx, order.RequestID)
+	if err != nil || got.Status != want.Status || got.SalesPrice != want.SalesPrice {
+		t.Errorf("CheckStatus() got = %v, %v, want %v", got, err, want)
+	}
+
+	if unused := replayer.Unused(); len(unused) != 0 {
+		t.Errorf("Unused() got = %v, want empty", unused)
+	}
+
+	_, err = cln.CheckStatus(ctx, "order-2")
+	if !errors.Is(err, ErrUnmatchedRequest) {
+		t.Errorf("CheckStatus() error = %v, want %v", err, ErrUnmatchedRequest)
+	}
+}
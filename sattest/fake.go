@@ -0,0 +1,182 @@
+package sattest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/google/jsonapi"
+	sat "github.com/tokopedia/golang-sat"
+)
+
+// ErrNotProgrammed is returned by FakeAPI when the operation func is not set
+var ErrNotProgrammed = errors.New("sattest: operation is not programmed")
+
+// Call contains the operation and its arguments received by FakeAPI
+type Call struct {
+	Operation sat.Operation
+	Args      []interface{}
+}
+
+// FakeAPI is a programmable fake of sat.API which records every call.
+// Set the operation func to program the response, the operation without func returns ErrNotProgrammed.
+// The call options are carried to the operation func by ctx
+type FakeAPI struct {
+	PingFunc        func(ctx context.Context) (*sat.PingResponse, error)
+	AccountFunc     func(ctx context.Context) (*sat.Account, error)
+	InquiryFunc     func(ctx context.Context, req *sat.InquiryRequest) (*sat.InquiryResponse, error)
+	CheckoutFunc    func(ctx context.Context, req *sat.OrderRequest) (*sat.OrderDetail, error)
+	CheckStatusFunc func(ctx context.Context, requestID string) (*sat.OrderDetail, error)
+	ListProductFunc func(ctx context.Context, code string) ([]*sat.Product, error)
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+var _ sat.API = (*FakeAPI)(nil)
+
+// Ping records the call and calls PingFunc
+func (f *FakeAPI) Ping(ctx context.Context, opts ...sat.CallOptionFunc) (*sat.PingResponse, error) {
+	f.record(sat.OperationPing)
+	if f.PingFunc == nil {
+		return nil, ErrNotProgrammed
+	}
+
+	return f.PingFunc(sat.ContextWithCallOptions(ctx, opts...))
+}
+
+// Account records the call and calls AccountFunc
+func (f *FakeAPI) Account(ctx context.Context, opts ...sat.CallOptionFunc) (*sat.Account, error) {
+	f.record(sat.OperationAccount)
+	if f.AccountFunc == nil {
+		return nil, ErrNotProgrammed
+	}
+
+	return f.AccountFunc(sat.ContextWithCallOptions(ctx, opts...))
+}
+
+// Inquiry records the call and calls InquiryFunc
+func (f *FakeAPI) Inquiry(ctx context.Context, req *sat.InquiryRequest, opts ...sat.CallOptionFunc) (*sat.InquiryResponse, error) {
+	f.record(sat.OperationInquiry, req)
+	if f.InquiryFunc == nil {
+		return nil, ErrNotProgrammed
+	}
+
+	return f.InquiryFunc(sat.ContextWithCallOptions(ctx, opts...), req)
+}
+
+// Checkout records the call and calls CheckoutFunc
+func (f *FakeAPI) Checkout(ctx context.Context, req *sat.OrderRequest, opts ...sat.CallOptionFunc) (*sat.OrderDetail, error) {
+	f.record(sat.OperationCheckout, req)
+	if f.CheckoutFunc == nil {
+		return nil, ErrNotProgrammed
+	}
+
+	return f.CheckoutFunc(sat.ContextWithCallOptions(ctx, opts...), req)
+}
+
+// CheckStatus records the call and calls CheckStatusFunc
+func (f *FakeAPI) CheckStatus(ctx context.Context, requestID string, opts ...sat.CallOptionFunc) (*sat.OrderDetail, error) {
+	f.record(sat.OperationCheckStatus, requestID)
+	if f.CheckStatusFunc == nil {
+		return nil, ErrNotProgrammed
+	}
+
+	return f.CheckStatusFunc(sat.ContextWithCallOptions(ctx, opts...), requestID)
+}
+
+// ListProduct records the call and calls ListProductFunc
+func (f *FakeAPI) ListProduct(ctx context.Context, code string, opts ...sat.CallOptionFunc) ([]*sat.Product, error) {
+	f.record(sat.OperationListProduct, code)
+	if f.ListProductFunc == nil {
+		return nil, ErrNotProgrammed
+	}
+
+	return f.ListProductFunc(sat.ContextWithCallOptions(ctx, opts...), code)
+}
+
+// HandleCallback returns a handler decoding the callback without verifying the signature
+func (f *FakeAPI) HandleCallback(impl sat.Callback, opts ...sat.CallbackOptionFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		request := new(sat.OrderDetail)
+		err := jsonapi.UnmarshalPayload(req.Body, request)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(sat.INVALID_PAYLOAD))
+			return
+		}
+
+		err = impl.Do(req.Context(), request)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(sat.SUCCESS_OK))
+	}
+}
+
+// Calls returns every call received in order
+func (f *FakeAPI) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]Call(nil), f.calls...)
+}
+
+// CallsOf returns every call of the operation in order
+func (f *FakeAPI) CallsOf(op sat.Operation) []Call {
+	var calls []Call
+	for _, c := range f.Calls() {
+		if c.Operation == op {
+			calls = append(calls, c)
+		}
+	}
+
+	return calls
+}
+
+// Reset removes every recorded call
+func (f *FakeAPI) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = nil
+}
+
+// AssertCalled fails the test when the operation is not called exactly n times
+func (f *FakeAPI) AssertCalled(t testing.TB, op sat.Operation, n int) {
+	t.Helper()
+
+	if got := len(f.CallsOf(op)); got != n {
+		t.Errorf("%s called %d times, want %d", op, got, n)
+	}
+}
+
+// AssertCalledWith fails the test when the last call of the operation doesn't receive the argument
+func (f *FakeAPI) AssertCalledWith(t testing.TB, op sat.Operation, arg interface{}) {
+	t.Helper()
+
+	calls := f.CallsOf(op)
+	if len(calls) == 0 {
+		t.Errorf("%s is never called", op)
+		return
+	}
+
+	last := calls[len(calls)-1]
+	if len(last.Args) == 0 || !reflect.DeepEqual(last.Args[0], arg) {
+		t.Errorf("%s called with %v, want %v", op, last.Args, arg)
+	}
+}
+
+func (f *FakeAPI) record(op sat.Operation, args ...interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, Call{Operation: op, Args: args})
+}
@@ -0,0 +1,42 @@
+package sattest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	sat "github.com/tokopedia/golang-sat"
+)
+
+func TestFakeAPI(t *testing.T) {
+	ctx := context.Background()
+	fake := &FakeAPI{
+		CheckStatusFunc: func(ctx context.Context, requestID string) (*sat.OrderDetail, error) {
+			return &sat.OrderDetail{RequestID: requestID, Status: sat.OrderStatusSuccess}, nil
+		},
+	}
+
+	var observed []sat.Operation
+	api := sat.Chain(fake, sat.Observe(func(ctx context.Context, op sat.Operation, duration time.Duration, err error) {
+		observed = append(observed, op)
+	}))
+
+	detail, err := api.CheckStatus(ctx, "order-1")
+	if err != nil || detail.Status != sat.OrderStatusSuccess {
+		t.Errorf("CheckStatus() got = %v, %v", detail, err)
+	}
+
+	_, err = api.Ping(ctx)
+	if !errors.Is(err, ErrNotProgrammed) {
+		t.Errorf("Ping() error = %v, want %v", err, ErrNotProgrammed)
+	}
+
+	fake.AssertCalled(t, sat.OperationCheckStatus, 1)
+	fake.AssertCalledWith(t, sat.OperationCheckStatus, "order-1")
+	fake.AssertCalled(t, sat.OperationCheckout, 0)
+
+	if len(observed) != 2 || observed[0] != sat.OperationCheckStatus || observed[1] != sat.OperationPing {
+		t.Errorf("Observe() got = %v", observed)
+	}
+}
@@ -0,0 +1,266 @@
+package sattest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	sat "github.com/tokopedia/golang-sat"
+)
+
+// SignatureFault is the way the fake server tampers the signature header
+type SignatureFault int
+
+const (
+	// SignatureValid keeps the signature as it is
+	SignatureValid SignatureFault = 0
+	// SignatureInvalid replaces the signature with an invalid one
+	SignatureInvalid SignatureFault = 1
+	// SignatureMissing removes the signature header
+	SignatureMissing SignatureFault = 2
+)
+
+// Rule applies the fault to every request matched by its endpoint, product code and client number.
+// The empty matcher matches everything
+type Rule struct {
+	// Endpoint is the SAT path constant, example sat.CHECKOUT_PATH, sat.CHECK_STATUS_PATH, TOKEN_PATH or CALLBACK_ENDPOINT
+	Endpoint     string
+	ProductCode  string
+	ClientNumber string
+	// Probability is the chance the fault is applied on matched request, zero means always
+	Probability float64
+	// Times limits how many times the fault is applied, zero means unlimited
+	Times int
+	Fault Fault
+}
+
+// Fault describes how the fake server misbehaves
+type Fault struct {
+	// Delay holds the response or the callback delivery
+	Delay time.Duration
+	// StatusCode replaces the response with this status code, Header, ContentType and Body
+	StatusCode  int
+	Header      http.Header
+	ContentType string
+	Body        string
+	// Signature tampers the signature header of the response or the callback
+	Signature SignatureFault
+	// StuckPending keeps the order pending until Resolve is called, only applied on checkout
+	StuckPending bool
+	// DuplicateCallbacks delivers the same callback again n times, only applied on callback
+	DuplicateCallbacks int
+	// OutOfOrderCallback delivers a stale pending callback after the final one, only applied on callback
+	OutOfOrderCallback bool
+}
+
+// Delay holds the response or the callback delivery
+func Delay(d time.Duration) Fault {
+	return Fault{Delay: d}
+}
+
+// TooManyRequests responds 429 with Retry-After header
+func TooManyRequests(retryAfter time.Duration) Fault {
+	return Fault{
+		StatusCode:  http.StatusTooManyRequests,
+		Header:      http.Header{"Retry-After": []string{strconv.Itoa(int(retryAfter / time.Second))}},
+		ContentType: "text/plain; charset=utf-8",
+		Body:        http.StatusText(http.StatusTooManyRequests),
+	}
+}
+
+// ServerError responds the standard SAT error payload with 5xx status code
+func ServerError(statusCode int) Fault {
+	return APIError(statusCode, ERROR_CODE_INTERNAL_SERVER, http.StatusText(statusCode))
+}
+
+// APIError responds the standard SAT error payload
+func APIError(statusCode int, code, detail string) Fault {
+	body, _ := json.Marshal(&sat.ErrorResponse{
+		Errors: []*sat.ErrorObject{
+			{
+				Status: strconv.Itoa(statusCode),
+				Code:   code,
+				Detail: detail,
+			},
+		},
+	})
+
+	return Fault{
+		StatusCode:  statusCode,
+		ContentType: "application/json",
+		Body:        string(body),
+	}
+}
+
+// HTMLError responds an html error page like produced by a proxy or a firewall
+func HTMLError(statusCode int) Fault {
+	text := strconv.Itoa(statusCode) + " " + http.StatusText(statusCode)
+	return Fault{
+		StatusCode:  statusCode,
+		ContentType: "text/html; charset=utf-8",
+		Body:        "<!DOCTYPE html>\n<html>\n<head><title>" + text + "</title></head>\n<body>\n<h1>" + text + "</h1>\n</body>\n</html>\n",
+	}
+}
+
+// InvalidSignature replaces the signature with an invalid one
+func InvalidSignature() Fault {
+	return Fault{Signature: SignatureInvalid}
+}
+
+// MissingSignature removes the signature header
+func MissingSignature() Fault {
+	return Fault{Signature: SignatureMissing}
+}
+
+// StuckPending keeps the order pending until Resolve is called
+func StuckPending() Fault {
+	return Fault{StuckPending: true}
+}
+
+// DuplicateCallbacks delivers the same callback again n times
+func DuplicateCallbacks(n int) Fault {
+	return Fault{DuplicateCallbacks: n}
+}
+
+// OutOfOrderCallback delivers a stale pending callback after the final one
+func OutOfOrderCallback() Fault {
+	return Fault{OutOfOrderCallback: true}
+}
+
+// Combine merges the faults, the later fault wins when both are set
+func Combine(faults ...Fault) Fault {
+	var f Fault
+	for _, fault := range faults {
+		if fault.Delay != 0 {
+			f.Delay = fault.Delay
+		}
+
+		if fault.StatusCode != 0 {
+			f.StatusCode = fault.StatusCode
+			f.Header = fault.Header
+			f.ContentType = fault.ContentType
+			f.Body = fault.Body
+		}
+
+		if fault.Signature != SignatureValid {
+			f.Signature = fault.Signature
+		}
+
+		if fault.DuplicateCallbacks != 0 {
+			f.DuplicateCallbacks = fault.DuplicateCallbacks
+		}
+
+		f.StuckPending = f.StuckPending || fault.StuckPending
+		f.OutOfOrderCallback = f.OutOfOrderCallback || fault.OutOfOrderCallback
+	}
+
+	return f
+}
+
+type rule struct {
+	Rule
+	hits int
+}
+
+type faultKey struct{}
+
+// faultFromContext returns the fault applied on the request being handled
+func faultFromContext(ctx context.Context) Fault {
+	f, _ := ctx.Value(faultKey{}).(Fault)
+	return f
+}
+
+// match returns the fault of the first rule matched, the rule's hits is counted.
+// The caller must hold s.mu
+func (s *Server) match(endpoint, productCode, clientNumber string) (Fault, bool) {
+	for _, r := range s.rules {
+		if r.Endpoint != "" && r.Endpoint != endpoint {
+			continue
+		}
+
+		if r.ProductCode != "" && r.ProductCode != productCode {
+			continue
+		}
+
+		if r.ClientNumber != "" && r.ClientNumber != clientNumber {
+			continue
+		}
+
+		if r.Times > 0 && r.hits >= r.Times {
+			continue
+		}
+
+		if r.Probability > 0 && s.rand.Float64() >= r.Probability {
+			continue
+		}
+
+		r.hits++
+		return r.Fault, true
+	}
+
+	return Fault{}, false
+}
+
+// sleep holds until the delay is passed or the context is done
+func sleep(ctx context.Context, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
+
+// writeFault writes the response replaced by the fault
+func writeFault(w http.ResponseWriter, f Fault) {
+	for k, v := range f.Header {
+		w.Header()[k] = v
+	}
+
+	if f.ContentType != "" {
+		w.Header().Set("Content-Type", f.ContentType)
+	}
+
+	w.WriteHeader(f.StatusCode)
+	w.Write([]byte(f.Body))
+}
+
+// signatureWriter tampers the signature header right before the header is written
+type signatureWriter struct {
+	http.ResponseWriter
+	fault       SignatureFault
+	wroteHeader bool
+}
+
+func (s *signatureWriter) WriteHeader(statusCode int) {
+	if !s.wroteHeader {
+		s.wroteHeader = true
+		tamperSignature(s.Header(), s.fault)
+	}
+
+	s.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (s *signatureWriter) Write(b []byte) (int, error) {
+	if !s.wroteHeader {
+		s.WriteHeader(http.StatusOK)
+	}
+
+	return s.ResponseWriter.Write(b)
+}
+
+func tamperSignature(h http.Header, fault SignatureFault) {
+	switch fault {
+	case SignatureInvalid:
+		h.Set(sat.SIGNATURE_HEADER_KEY, INVALID_SIGNATURE_VALUE)
+	case SignatureMissing:
+		h.Del(sat.SIGNATURE_HEADER_KEY)
+	}
+}
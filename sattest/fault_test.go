@@ -0,0 +1,157 @@
+package sattest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	sat "github.com/tokopedia/golang-sat"
+)
+
+func TestServer_Rules(t *testing.T) {
+	ctx := context.Background()
+	srv := NewServer(
+		WithTransition(StayPending),
+		WithRules(
+			Rule{Endpoint: sat.ACCOUNT_PATH, Times: 1, Fault: TooManyRequests(3 * time.Second)},
+			Rule{Endpoint: sat.PRODUCT_LIST_PATH, ProductCode: "pln-prepaid-token-20k", Fault: HTMLError(http.StatusBadGateway)},
+			Rule{Endpoint: sat.CHECK_STATUS_PATH, ClientNumber: "081200000000", Fault: InvalidSignature()},
+		),
+	)
+	defer srv.Close()
+
+	cln, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var errI sat.APIInternalError
+	_, err = cln.Account(ctx)
+	if !errors.As(err, &errI) || errI.Response().StatusCode != http.StatusTooManyRequests ||
+		errI.Response().Header.Get("Retry-After") != "3" {
+		t.Errorf("Account() error = %v, want 429 with Retry-After", err)
+	}
+
+	_, err = cln.Account(ctx)
+	if err != nil {
+		t.Errorf("Account() error = %v, want the rule is applied once", err)
+	}
+
+	_, err = cln.ListProduct(ctx, "pln-prepaid-token-20k")
+	if !errors.As(err, &errI) || errI.Response().StatusCode != http.StatusBadGateway {
+		t.Errorf("ListProduct() error = %v, want html 502", err)
+	}
+
+	_, err = cln.ListProduct(ctx, "pln-prepaid-token-50k")
+	if err != nil {
+		t.Errorf("ListProduct() error = %v, want other product is not matched", err)
+	}
+
+	_, err = cln.Checkout(ctx, &sat.OrderRequest{
+		RequestID:    "order-1",
+		ProductCode:  "telkomsel-10k",
+		ClientNumber: "081200000000",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = cln.CheckStatus(ctx, "order-1")
+	if err == nil {
+		t.Errorf("CheckStatus() error = nil, want invalid signature")
+	}
+}
+
+func TestServer_CallbackRules(t *testing.T) {
+	received := make(chan string, 10)
+	callbackServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		received <- req.Header.Get(sat.SIGNATURE_HEADER_KEY)
+		w.Write([]byte(sat.SUCCESS_OK))
+	}))
+	defer callbackServer.Close()
+
+	srv := NewServer(
+		WithCallbackURL(callbackServer.URL),
+		WithRules(
+			Rule{Endpoint: sat.CHECKOUT_PATH, ProductCode: "pln-prepaid-token-20k", Fault: StuckPending()},
+			Rule{Endpoint: CALLBACK_ENDPOINT, Fault: Combine(DuplicateCallbacks(1), OutOfOrderCallback())},
+		),
+	)
+	defer srv.Close()
+
+	cln, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, code := range []string{"pln-prepaid-token-20k", "telkomsel-10k"} {
+		_, err = cln.Checkout(context.Background(), &sat.OrderRequest{
+			RequestID:    "order-" + strconv.Itoa(i),
+			ProductCode:  code,
+			ClientNumber: "081200000000",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-received:
+		case <-time.After(3 * time.Second):
+			t.Fatalf("Callback %d is not delivered", i)
+		}
+	}
+	srv.Close()
+
+	deliveries := srv.Deliveries()
+	want := []string{sat.OrderStatusSuccess, sat.OrderStatusSuccess, sat.OrderStatusPending}
+	if len(deliveries) != len(want) {
+		t.Fatalf("Deliveries() got = %v, want %v", deliveries, want)
+	}
+
+	for i, d := range deliveries {
+		if d.RequestID != "order-1" || d.Status != want[i] {
+			t.Errorf("Deliveries()[%d] got = %v, want order-1 %s", i, d, want[i])
+		}
+	}
+
+	detail, _ := srv.Order("order-0")
+	if detail.Status != sat.OrderStatusPending {
+		t.Errorf("Order() got = %s, want stuck pending", detail.Status)
+	}
+}
+
+func TestServer_Seed(t *testing.T) {
+	run := func(seed int64) []bool {
+		srv := NewServer(
+			WithSeed(seed),
+			WithRules(Rule{Endpoint: sat.PING_PATH, Probability: 0.5, Fault: ServerError(http.StatusServiceUnavailable)}),
+		)
+		defer srv.Close()
+
+		cln, err := srv.NewClient()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var failed []bool
+		for i := 0; i < 20; i++ {
+			_, err = cln.Ping(context.Background())
+			failed = append(failed, err != nil)
+		}
+
+		return failed
+	}
+
+	first, second := run(42), run(42)
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("run(42) got = %v and %v, want the same faults", first, second)
+		}
+	}
+}
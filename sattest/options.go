@@ -0,0 +1,197 @@
+package sattest
+
+import (
+	"net/http"
+	"time"
+
+	sat "github.com/tokopedia/golang-sat"
+	"github.com/tokopedia/golang-sat/signature"
+)
+
+// Outcome contains the final state of an order and when it will be reached
+type Outcome struct {
+	// Status is the final order status, sat.OrderStatusPending will keep the order pending until Resolve is called
+	Status      string
+	ErrorCode   string
+	ErrorDetail string
+	// After is the delay from the checkout until the order reaches the final status
+	After time.Duration
+}
+
+// TransitionFunc decides the outcome of every order received by the fake server
+type TransitionFunc func(req *sat.OrderRequest) Outcome
+
+// Option contains field you can configure on the fake server
+type Option struct {
+	balance          int64
+	adminFee         int64
+	products         []*sat.Product
+	callbackURL      string
+	callbackClient   *http.Client
+	transition       TransitionFunc
+	paddingType      signature.PaddingType
+	serverPrivateKey string
+	clientPublicKey  string
+	seed             int64
+	rules            []Rule
+}
+
+var defaultOption = Option{
+	balance:          DEFAULT_BALANCE,
+	adminFee:         DEFAULT_ADMIN_FEE,
+	products:         DefaultProducts(),
+	callbackClient:   &http.Client{Timeout: 5 * time.Second},
+	transition:       SucceedImmediately,
+	paddingType:      signature.PaddingTypePSS,
+	serverPrivateKey: SERVER_PRIVATE_KEY,
+	clientPublicKey:  CLIENT_PUBLIC_KEY,
+	seed:             DEFAULT_SEED,
+}
+
+type OptionFunc func(*Option)
+
+// WithBalance set the initial account balance
+func WithBalance(balance int64) OptionFunc {
+	return func(o *Option) {
+		o.balance = balance
+	}
+}
+
+// WithAdminFee set the admin fee charged for inquiry products
+func WithAdminFee(adminFee int64) OptionFunc {
+	return func(o *Option) {
+		o.adminFee = adminFee
+	}
+}
+
+// WithProducts override the default product catalog
+func WithProducts(products ...*sat.Product) OptionFunc {
+	return func(o *Option) {
+		o.products = products
+	}
+}
+
+// WithCallbackURL set the URL receiving signed callbacks when an order reaches the final status
+func WithCallbackURL(callbackURL string) OptionFunc {
+	return func(o *Option) {
+		o.callbackURL = callbackURL
+	}
+}
+
+// WithCallbackClient customizes http client used to deliver callbacks
+func WithCallbackClient(callbackClient *http.Client) OptionFunc {
+	return func(o *Option) {
+		o.callbackClient = callbackClient
+	}
+}
+
+// WithTransition set the order lifecycle
+func WithTransition(transition TransitionFunc) OptionFunc {
+	return func(o *Option) {
+		o.transition = transition
+	}
+}
+
+// WithPaddingType set specific padding type for sign & verify signature
+func WithPaddingType(paddingType signature.PaddingType) OptionFunc {
+	return func(o *Option) {
+		o.paddingType = paddingType
+	}
+}
+
+// WithKeys override the server private key and client public key
+func WithKeys(serverPrivateKey, clientPublicKey string) OptionFunc {
+	return func(o *Option) {
+		o.serverPrivateKey = serverPrivateKey
+		o.clientPublicKey = clientPublicKey
+	}
+}
+
+// WithSeed set the seed of the random source used by the probability of the rules,
+// the same seed and the same requests will always produce the same faults
+func WithSeed(seed int64) OptionFunc {
+	return func(o *Option) {
+		o.seed = seed
+	}
+}
+
+// WithRules set the fault injection rules, see Server.AddRule
+func WithRules(rules ...Rule) OptionFunc {
+	return func(o *Option) {
+		o.rules = append(o.rules, rules...)
+	}
+}
+
+// SucceedImmediately is the default transition, every order will be success right after the checkout
+func SucceedImmediately(req *sat.OrderRequest) Outcome {
+	return Outcome{Status: sat.OrderStatusSuccess}
+}
+
+// StayPending is a transition which keeps every order pending until Resolve is called
+func StayPending(req *sat.OrderRequest) Outcome {
+	return Outcome{Status: sat.OrderStatusPending}
+}
+
+// DefaultProducts returns the default product catalog of the fake server
+func DefaultProducts() []*sat.Product {
+	return []*sat.Product{
+		{
+			Name:         "Token PLN 20.000",
+			Code:         "pln-prepaid-token-20k",
+			OperatorName: "PLN",
+			CategoryName: "Listrik PLN",
+			SalesPrice:   20500,
+			Status:       sat.ProductStatusActive,
+		},
+		{
+			Name:         "Token PLN 50.000",
+			Code:         "pln-prepaid-token-50k",
+			OperatorName: "PLN",
+			CategoryName: "Listrik PLN",
+			SalesPrice:   50500,
+			Status:       sat.ProductStatusActive,
+		},
+		{
+			Name:         "Token PLN 100.000",
+			Code:         "pln-prepaid-token-100k",
+			OperatorName: "PLN",
+			CategoryName: "Listrik PLN",
+			SalesPrice:   100500,
+			Status:       sat.ProductStatusActive,
+		},
+		{
+			Name:         "Tagihan PLN",
+			Code:         "pln-postpaid",
+			OperatorName: "PLN",
+			CategoryName: "Listrik PLN",
+			IsInquiry:    true,
+			SalesPrice:   150000,
+			Status:       sat.ProductStatusActive,
+		},
+		{
+			Name:         "Pulsa Telkomsel 10.000",
+			Code:         "telkomsel-10k",
+			OperatorName: "Telkomsel",
+			CategoryName: "Pulsa",
+			SalesPrice:   10200,
+			Status:       sat.ProductStatusActive,
+		},
+		{
+			Name:         "Pulsa Indosat 10.000",
+			Code:         "indosat-10k",
+			OperatorName: "Indosat",
+			CategoryName: "Pulsa",
+			SalesPrice:   10300,
+			Status:       sat.ProductTempInactive,
+		},
+		{
+			Name:         "BPJS Kesehatan",
+			Code:         "bpjs-kesehatan",
+			OperatorName: "BPJS",
+			CategoryName: "BPJS",
+			IsInquiry:    true,
+			SalesPrice:   80000,
+			Status:       sat.ProductStatusActive,
+		},
+	}
+}
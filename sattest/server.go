@@ -0,0 +1,706 @@
+// Package sattest provides an in-process stateful fake of the SAT server for offline testing.
+// The fake server exposes the oauth token endpoint and every SAT endpoint supported by the SDK,
+// signs its responses and delivers signed callbacks, so it can be used with WithSatBaseURL and WithAccessTokenURL
+package sattest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/jsonapi"
+	sat "github.com/tokopedia/golang-sat"
+	"github.com/tokopedia/golang-sat/signature"
+)
+
+// Server is an in-process stateful fake of the SAT server
+type Server struct {
+	srv       *httptest.Server
+	opt       Option
+	signature *signature.Signature
+	ctx       context.Context
+	cancel    context.CancelFunc
+
+	mu         sync.Mutex
+	seq        int64
+	balance    int64
+	products   []*sat.Product
+	inquiries  map[string]*sat.InquiryResponse
+	orders     map[string]*order
+	deliveries []Delivery
+	rules      []*rule
+	rand       *rand.Rand
+	closed     bool
+	wg         sync.WaitGroup
+}
+
+type order struct {
+	request *sat.OrderRequest
+	detail  *sat.OrderDetail
+	timer   *time.Timer
+}
+
+// Delivery contains the result of a callback delivered by the fake server
+type Delivery struct {
+	RequestID  string
+	Status     string
+	StatusCode int
+	Body       string
+	Err        error
+}
+
+// NewServer will start and return a new fake SAT server, Close must be called when it is no longer used
+func NewServer(opts ...OptionFunc) *Server {
+	opt := defaultOption
+	for _, option := range opts {
+		option(&opt)
+	}
+
+	s := &Server{
+		opt: opt,
+		signature: signature.Init(signature.Options{
+			PrivateKeyString: opt.serverPrivateKey,
+			PublicKeyString:  opt.clientPublicKey,
+			PaddingType:      opt.paddingType,
+		}),
+		balance:   opt.balance,
+		inquiries: map[string]*sat.InquiryResponse{},
+		orders:    map[string]*order{},
+		rand:      rand.New(rand.NewSource(opt.seed)),
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+
+	for _, p := range opt.products {
+		s.SetProduct(p)
+	}
+
+	for _, r := range opt.rules {
+		s.AddRule(r)
+	}
+
+	s.srv = httptest.NewServer(http.HandlerFunc(s.ServeHTTP))
+	return s
+}
+
+// URL returns the base URL of the fake server, use it on sat.WithSatBaseURL
+func (s *Server) URL() string {
+	return s.srv.URL
+}
+
+// TokenURL returns the oauth token URL of the fake server, use it on sat.WithAccessTokenURL
+func (s *Server) TokenURL() string {
+	return s.srv.URL + TOKEN_PATH
+}
+
+// Close shuts down the fake server and waits until every callback in flight is delivered,
+// the delayed callbacks are dropped
+func (s *Server) Close() {
+	s.cancel()
+	s.mu.Lock()
+	s.closed = true
+	for _, o := range s.orders {
+		if o.timer != nil {
+			o.timer.Stop()
+		}
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	s.srv.Close()
+}
+
+// NewClient will return a new sat.Client already configured to the fake server
+func (s *Server) NewClient(opts ...sat.ClientOptionFunc) (*sat.Client, error) {
+	opts = append([]sat.ClientOptionFunc{
+		sat.WithServerPublicKeyString(SERVER_PUBLIC_KEY),
+		sat.WithPaddingType(s.opt.paddingType),
+		sat.WithSatBaseURL(s.URL()),
+		sat.WithAccessTokenURL(s.TokenURL()),
+	}, opts...)
+
+	return sat.NewClient("sattest-client-id", "sattest-client-secret", CLIENT_PRIVATE_KEY, opts...)
+}
+
+// Balance returns the current account balance
+func (s *Server) Balance() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.balance
+}
+
+// SetBalance override the current account balance
+func (s *Server) SetBalance(balance int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.balance = balance
+}
+
+// Products returns the current product catalog
+func (s *Server) Products() []*sat.Product {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	products := make([]*sat.Product, 0, len(s.products))
+	for _, p := range s.products {
+		cp := *p
+		products = append(products, &cp)
+	}
+
+	return products
+}
+
+// SetProduct adds a new product or replaces the product with the same code
+func (s *Server) SetProduct(product *sat.Product) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cp := *product
+	for i, p := range s.products {
+		if p.Code == product.Code {
+			s.products[i] = &cp
+			return
+		}
+	}
+
+	s.products = append(s.products, &cp)
+}
+
+// RemoveProduct removes the product from the catalog
+func (s *Server) RemoveProduct(code string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, p := range s.products {
+		if p.Code == code {
+			s.products = append(s.products[:i], s.products[i+1:]...)
+			return
+		}
+	}
+}
+
+// Order returns the current state of the order
+func (s *Server) Order(requestID string) (*sat.OrderDetail, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, ok := s.orders[requestID]
+	if !ok {
+		return nil, false
+	}
+
+	detail := *o.detail
+	return &detail, true
+}
+
+// Resolve moves a pending order to the outcome status and delivers the callback.
+// Outcome.After is ignored, the order is resolved immediately
+func (s *Server) Resolve(requestID string, outcome Outcome) error {
+	s.mu.Lock()
+	o, ok := s.orders[requestID]
+	if ok && o.timer != nil {
+		o.timer.Stop()
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("sattest: order %s is not found", requestID)
+	}
+
+	s.finalize(requestID, outcome)
+	return nil
+}
+
+// AddRule appends a fault injection rule, the first rule matched by the request will be applied
+func (s *Server) AddRule(r Rule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rules = append(s.rules, &rule{Rule: r})
+}
+
+// ClearRules removes every fault injection rule
+func (s *Server) ClearRules() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rules = nil
+}
+
+// Seed returns the seed of the random source, log it to reproduce a failure
+func (s *Server) Seed() int64 {
+	return s.opt.seed
+}
+
+// Deliveries returns every callback delivered by the fake server
+func (s *Server) Deliveries() []Delivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Delivery(nil), s.deliveries...)
+}
+
+// ServeHTTP handles every request of the fake server
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	endpoint := endpointOf(req)
+	productCode, clientNumber := s.keysOf(req, endpoint)
+
+	s.mu.Lock()
+	fault, ok := s.match(endpoint, productCode, clientNumber)
+	s.mu.Unlock()
+
+	if ok {
+		sleep(req.Context(), fault.Delay)
+		if fault.StatusCode != 0 {
+			writeFault(w, fault)
+			return
+		}
+
+		if fault.Signature != SignatureValid {
+			w = &signatureWriter{ResponseWriter: w, fault: fault.Signature}
+		}
+
+		req = req.WithContext(context.WithValue(req.Context(), faultKey{}, fault))
+	}
+
+	if endpoint == TOKEN_PATH {
+		s.handleToken(w, req)
+		return
+	}
+
+	if req.Header.Get("Authorization") != "Bearer "+ACCESS_TOKEN {
+		writeError(w, http.StatusUnauthorized, ERROR_CODE_UNAUTHORIZED, "access token is invalid")
+		return
+	}
+
+	switch endpoint {
+	case sat.PING_PATH:
+		s.handlePing(w, req)
+	case sat.ACCOUNT_PATH:
+		s.handleAccount(w, req)
+	case sat.PRODUCT_LIST_PATH:
+		s.handleListProduct(w, req)
+	case sat.INQUIRY_PATH:
+		s.handleInquiry(w, req)
+	case sat.CHECKOUT_PATH:
+		s.handleCheckout(w, req)
+	case sat.CHECK_STATUS_PATH:
+		s.handleCheckStatus(w, req, strings.TrimPrefix(req.URL.Path, sat.CHECKOUT_PATH+"/"))
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+// endpointOf returns the SAT path constant of the request
+func endpointOf(req *http.Request) string {
+	switch {
+	case req.URL.Path == TOKEN_PATH:
+		return TOKEN_PATH
+	case req.Method == http.MethodGet && req.URL.Path == sat.PING_PATH:
+		return sat.PING_PATH
+	case req.Method == http.MethodGet && req.URL.Path == sat.ACCOUNT_PATH:
+		return sat.ACCOUNT_PATH
+	case req.Method == http.MethodGet && req.URL.Path == sat.PRODUCT_LIST_PATH:
+		return sat.PRODUCT_LIST_PATH
+	case req.Method == http.MethodPost && req.URL.Path == sat.INQUIRY_PATH:
+		return sat.INQUIRY_PATH
+	case req.Method == http.MethodPost && req.URL.Path == sat.CHECKOUT_PATH:
+		return sat.CHECKOUT_PATH
+	case req.Method == http.MethodGet && strings.HasPrefix(req.URL.Path, sat.CHECKOUT_PATH+"/"):
+		return sat.CHECK_STATUS_PATH
+	default:
+		return req.URL.Path
+	}
+}
+
+// keysOf returns the product code and client number of the request used to match the rules,
+// the request body is restored so the handler can read it again
+func (s *Server) keysOf(req *http.Request, endpoint string) (string, string) {
+	switch endpoint {
+	case sat.PRODUCT_LIST_PATH:
+		return req.URL.Query().Get("product_code"), ""
+	case sat.CHECK_STATUS_PATH:
+		detail, ok := s.Order(strings.TrimPrefix(req.URL.Path, sat.CHECKOUT_PATH+"/"))
+		if !ok {
+			return "", ""
+		}
+
+		return detail.ProductCode, detail.ClientNumber
+	case sat.INQUIRY_PATH, sat.CHECKOUT_PATH:
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return "", ""
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+
+		var payload struct {
+			Data struct {
+				Attributes struct {
+					ProductCode  string `json:"product_code"`
+					ClientNumber string `json:"client_number"`
+				} `json:"attributes"`
+			} `json:"data"`
+		}
+		err = json.Unmarshal(body, &payload)
+		if err != nil {
+			return "", ""
+		}
+
+		return payload.Data.Attributes.ProductCode, payload.Data.Attributes.ClientNumber
+	default:
+		return "", ""
+	}
+}
+
+func (s *Server) handleToken(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": ACCESS_TOKEN,
+		"token_type":   "Bearer",
+		"expires_in":   86400,
+	})
+}
+
+func (s *Server) handlePing(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&sat.PingResponse{
+		Buildhash: "sattest",
+		Sandbox:   true,
+		Status:    "ok",
+	})
+}
+
+func (s *Server) handleAccount(w http.ResponseWriter, req *http.Request) {
+	s.writePayload(w, &sat.Account{
+		ID:    1,
+		Saldo: s.Balance(),
+	})
+}
+
+func (s *Server) handleListProduct(w http.ResponseWriter, req *http.Request) {
+	code := req.URL.Query().Get("product_code")
+	products := s.Products()
+	if code == "" {
+		s.writePayload(w, products)
+		return
+	}
+
+	for _, p := range products {
+		if p.Code == code {
+			s.writePayload(w, []*sat.Product{p})
+			return
+		}
+	}
+
+	writeError(w, http.StatusNotFound, ERROR_CODE_PRODUCT_NOT_FOUND, "product is not found")
+}
+
+func (s *Server) handleInquiry(w http.ResponseWriter, req *http.Request) {
+	inq := new(sat.InquiryRequest)
+	err := jsonapi.UnmarshalPayload(req.Body, inq)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ERROR_CODE_INVALID_PAYLOAD, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	product, status, code, detail := s.findProduct(inq.ProductCode)
+	if product != nil && !product.IsInquiry {
+		product, status, code, detail = nil, http.StatusBadRequest, ERROR_CODE_NOT_INQUIRY_PRODUCT, "product doesn't support inquiry"
+	}
+
+	if product == nil {
+		s.mu.Unlock()
+		writeError(w, status, code, detail)
+		return
+	}
+
+	s.seq++
+	response := &sat.InquiryResponse{
+		ID:           "inquiry-" + strconv.FormatInt(s.seq, 10),
+		ProductCode:  product.Code,
+		SalesPrice:   product.SalesPrice + s.opt.adminFee,
+		Fields:       inq.Fields,
+		BasePrice:    product.SalesPrice,
+		AdminFee:     s.opt.adminFee,
+		ClientName:   "SAT TEST",
+		ClientNumber: inq.ClientNumber,
+		RefID:        "ref-" + strconv.FormatInt(s.seq, 10),
+		InquiryResult: sat.Fields{
+			{Name: "Nama Pelanggan", Value: "SAT TEST"},
+			{Name: "Total Tagihan", Value: strconv.FormatInt(product.SalesPrice+s.opt.adminFee, 10)},
+		},
+	}
+	s.inquiries[inquiryKey(product.Code, inq.ClientNumber)] = response
+	s.mu.Unlock()
+
+	s.writePayload(w, response)
+}
+
+func (s *Server) handleCheckout(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ERROR_CODE_INVALID_PAYLOAD, err.Error())
+		return
+	}
+
+	err = s.signature.Verify(string(body), req.Header.Get(sat.SIGNATURE_HEADER_KEY))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, ERROR_CODE_INVALID_SIGNATURE, err.Error())
+		return
+	}
+
+	orderReq := new(sat.OrderRequest)
+	err = jsonapi.UnmarshalPayload(bytes.NewReader(body), orderReq)
+	if err != nil || orderReq.RequestID == "" {
+		writeError(w, http.StatusBadRequest, ERROR_CODE_INVALID_PAYLOAD, "request id can't be empty")
+		return
+	}
+
+	s.mu.Lock()
+	if existing, ok := s.orders[orderReq.RequestID]; ok {
+		detail := *existing.detail
+		s.mu.Unlock()
+		s.writePayload(w, &detail)
+		return
+	}
+
+	product, status, code, detail := s.findProduct(orderReq.ProductCode)
+	if product == nil {
+		s.mu.Unlock()
+		writeError(w, status, code, detail)
+		return
+	}
+
+	price, adminFee := product.SalesPrice, int64(0)
+	if product.IsInquiry {
+		inq, ok := s.inquiries[inquiryKey(product.Code, orderReq.ClientNumber)]
+		if !ok {
+			s.mu.Unlock()
+			writeError(w, http.StatusBadRequest, ERROR_CODE_INQUIRY_REQUIRED, "inquiry is required before checkout")
+			return
+		}
+
+		price, adminFee = inq.SalesPrice, inq.AdminFee
+	}
+
+	if s.balance < price {
+		s.mu.Unlock()
+		writeError(w, http.StatusBadRequest, ERROR_CODE_INSUFFICIENT_BALANCE, "balance is insufficient")
+		return
+	}
+
+	s.balance -= price
+	o := &order{
+		request: orderReq,
+		detail: &sat.OrderDetail{
+			RequestID:    orderReq.RequestID,
+			Fields:       orderReq.Fields,
+			ProductCode:  product.Code,
+			Status:       sat.OrderStatusPending,
+			SalesPrice:   price,
+			AdminFee:     adminFee,
+			ClientNumber: orderReq.ClientNumber,
+		},
+	}
+	s.orders[orderReq.RequestID] = o
+
+	outcome := s.opt.transition(orderReq)
+	if faultFromContext(req.Context()).StuckPending {
+		outcome = Outcome{Status: sat.OrderStatusPending}
+	}
+
+	if outcome.Status != "" && outcome.Status != sat.OrderStatusPending {
+		o.timer = time.AfterFunc(outcome.After, func() {
+			s.finalize(orderReq.RequestID, outcome)
+		})
+	}
+
+	response := *o.detail
+	s.mu.Unlock()
+
+	s.writePayload(w, &response)
+}
+
+func (s *Server) handleCheckStatus(w http.ResponseWriter, req *http.Request, requestID string) {
+	detail, ok := s.Order(requestID)
+	if !ok {
+		writeError(w, http.StatusNotFound, ERROR_CODE_ORDER_NOT_FOUND, "order is not found")
+		return
+	}
+
+	s.writePayload(w, detail)
+}
+
+// findProduct returns the active product, or the error response when it can't be ordered.
+// The caller must hold s.mu
+func (s *Server) findProduct(code string) (*sat.Product, int, string, string) {
+	for _, p := range s.products {
+		if p.Code != code {
+			continue
+		}
+
+		if p.Status != sat.ProductStatusActive {
+			return nil, http.StatusBadRequest, ERROR_CODE_PRODUCT_INACTIVE, "product is not active"
+		}
+
+		return p, http.StatusOK, "", ""
+	}
+
+	return nil, http.StatusNotFound, ERROR_CODE_PRODUCT_NOT_FOUND, "product is not found"
+}
+
+// finalize moves the order to the final status, refunds the balance of failed order and delivers the callback
+func (s *Server) finalize(requestID string, outcome Outcome) {
+	s.mu.Lock()
+	o, ok := s.orders[requestID]
+	if !ok || s.closed || o.detail.Status != sat.OrderStatusPending {
+		s.mu.Unlock()
+		return
+	}
+
+	stale := *o.detail
+	now := time.Now()
+	o.detail.Status = outcome.Status
+	o.detail.ErrorCode = outcome.ErrorCode
+	o.detail.ErrorDetail = outcome.ErrorDetail
+	switch outcome.Status {
+	case sat.OrderStatusSuccess:
+		o.detail.FulfilledAt = &now
+		o.detail.SerialNumber = "SN" + strconv.FormatInt(now.UnixNano(), 10)
+	case sat.OrderStatusFailed:
+		s.balance += o.detail.SalesPrice
+	}
+
+	detail := *o.detail
+	if s.opt.callbackURL != "" {
+		s.wg.Add(1)
+	}
+	s.mu.Unlock()
+
+	if s.opt.callbackURL != "" {
+		go func() {
+			defer s.wg.Done()
+			s.deliverAll(&detail, &stale)
+		}()
+	}
+}
+
+// deliverAll sends the callback of the final order, and the duplicate or stale callbacks when the fault is matched
+func (s *Server) deliverAll(detail, stale *sat.OrderDetail) {
+	s.mu.Lock()
+	fault, _ := s.match(CALLBACK_ENDPOINT, detail.ProductCode, detail.ClientNumber)
+	s.mu.Unlock()
+
+	sleep(s.ctx, fault.Delay)
+	if s.ctx.Err() != nil {
+		return
+	}
+
+	for i := 0; i <= fault.DuplicateCallbacks; i++ {
+		s.deliver(detail, fault.Signature)
+	}
+
+	if fault.OutOfOrderCallback {
+		s.deliver(stale, fault.Signature)
+	}
+}
+
+// deliver sends the signed callback to the callback URL
+func (s *Server) deliver(detail *sat.OrderDetail, signatureFault SignatureFault) {
+	result := Delivery{
+		RequestID: detail.RequestID,
+		Status:    detail.Status,
+	}
+
+	defer func() {
+		s.mu.Lock()
+		s.deliveries = append(s.deliveries, result)
+		s.mu.Unlock()
+	}()
+
+	body, sign, err := s.sign(detail)
+	if err != nil {
+		result.Err = err
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.opt.callbackURL, bytes.NewReader(body))
+	if err != nil {
+		result.Err = err
+		return
+	}
+
+	req.Header.Set("Content-Type", jsonapi.MediaType)
+	req.Header.Set(sat.SIGNATURE_HEADER_KEY, sign)
+	tamperSignature(req.Header, signatureFault)
+	resp, err := s.opt.callbackClient.Do(req)
+	if err != nil {
+		result.Err = err
+		return
+	}
+	defer resp.Body.Close()
+
+	b, _ := io.ReadAll(resp.Body)
+	result.StatusCode = resp.StatusCode
+	result.Body = string(b)
+}
+
+// sign marshals the payload and signs it using the server private key
+func (s *Server) sign(payload interface{}) ([]byte, string, error) {
+	b := &bytes.Buffer{}
+	err := jsonapi.MarshalPayload(b, payload)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sign, err := s.signature.Sign(b.Bytes())
+	if err != nil {
+		return nil, "", err
+	}
+
+	return b.Bytes(), sign, nil
+}
+
+// writePayload writes the signed jsonapi payload
+func (s *Server) writePayload(w http.ResponseWriter, payload interface{}) {
+	body, sign, err := s.sign(payload)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ERROR_CODE_INVALID_PAYLOAD, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+	w.Header().Set(sat.SIGNATURE_HEADER_KEY, sign)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// writeError writes the standard SAT error payload
+func writeError(w http.ResponseWriter, status int, code, detail string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(&sat.ErrorResponse{
+		Errors: []*sat.ErrorObject{
+			{
+				Status: strconv.Itoa(status),
+				Code:   code,
+				Detail: detail,
+			},
+		},
+	})
+}
+
+func inquiryKey(productCode, clientNumber string) string {
+	return productCode + "|" + clientNumber
+}
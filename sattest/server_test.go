@@ -0,0 +1,164 @@
+package sattest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	sat "github.com/tokopedia/golang-sat"
+)
+
+type callbackChannel chan *sat.OrderDetail
+
+func (c callbackChannel) Do(ctx context.Context, request *sat.OrderDetail) error {
+	c <- request
+	return nil
+}
+
+func TestServer_OrderLifecycle(t *testing.T) {
+	ctx := context.Background()
+	callbacks := make(callbackChannel, 1)
+
+	var cln *sat.Client
+	callbackServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		cln.HandleCallback(callbacks)(w, req)
+	}))
+	defer callbackServer.Close()
+
+	srv := NewServer(
+		WithBalance(200000),
+		WithCallbackURL(callbackServer.URL),
+	)
+	defer srv.Close()
+
+	cln, err := srv.NewClient(sat.WithHTTPClient(&http.Client{Timeout: 3 * time.Second}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ping, err := cln.Ping(ctx)
+	if err != nil || ping.Status != "ok" {
+		t.Fatalf("Ping() got = %v, %v", ping, err)
+	}
+
+	products, err := cln.ListProduct(ctx, "pln-prepaid-token-50k")
+	if err != nil || len(products) != 1 || products[0].SalesPrice != 50500 {
+		t.Fatalf("ListProduct() got = %v, %v", products, err)
+	}
+
+	inq, err := cln.Inquiry(ctx, &sat.InquiryRequest{
+		ProductCode:  "pln-postpaid",
+		ClientNumber: "512345678901",
+	})
+	if err != nil || inq.SalesPrice != 150000+DEFAULT_ADMIN_FEE {
+		t.Fatalf("Inquiry() got = %v, %v", inq, err)
+	}
+
+	order, err := cln.Checkout(ctx, &sat.OrderRequest{
+		RequestID:    "order-1",
+		ProductCode:  "pln-postpaid",
+		ClientNumber: "512345678901",
+	})
+	if err != nil || order.Status != sat.OrderStatusPending {
+		t.Fatalf("Checkout() got = %v, %v", order, err)
+	}
+
+	select {
+	case callback := <-callbacks:
+		if callback.RequestID != "order-1" || callback.Status != sat.OrderStatusSuccess {
+			t.Errorf("Callback got = %v", callback)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Callback is not delivered")
+	}
+
+	status, err := cln.CheckStatus(ctx, "order-1")
+	if err != nil || status.Status != sat.OrderStatusSuccess {
+		t.Errorf("CheckStatus() got = %v, %v", status, err)
+	}
+
+	account, err := cln.Account(ctx)
+	if err != nil || account.Saldo != 200000-150000-DEFAULT_ADMIN_FEE {
+		t.Errorf("Account() got = %v, %v", account, err)
+	}
+
+	_, err = cln.Checkout(ctx, &sat.OrderRequest{
+		RequestID:    "order-2",
+		ProductCode:  "pln-prepaid-token-100k",
+		ClientNumber: "512345678901",
+	})
+
+	var errR sat.APIResponseError
+	if !errors.As(err, &errR) || errR.Code() != ERROR_CODE_INSUFFICIENT_BALANCE {
+		t.Errorf("Checkout() error = %v, want %s", err, ERROR_CODE_INSUFFICIENT_BALANCE)
+	}
+}
+
+func TestServer_Resolve(t *testing.T) {
+	ctx := context.Background()
+	srv := NewServer(WithTransition(StayPending))
+	defer srv.Close()
+
+	cln, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = cln.Checkout(ctx, &sat.OrderRequest{
+		RequestID:    "order-1",
+		ProductCode:  "telkomsel-10k",
+		ClientNumber: "081234567890",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if srv.Balance() != DEFAULT_BALANCE-10200 {
+		t.Errorf("Balance() got = %d, want %d", srv.Balance(), DEFAULT_BALANCE-10200)
+	}
+
+	err = srv.Resolve("order-1", Outcome{Status: sat.OrderStatusFailed, ErrorCode: "S00"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := cln.CheckStatus(ctx, "order-1")
+	if err != nil || status.Status != sat.OrderStatusFailed || status.ErrorCode != "S00" {
+		t.Errorf("CheckStatus() got = %v, %v", status, err)
+	}
+
+	if srv.Balance() != DEFAULT_BALANCE {
+		t.Errorf("Balance() got = %d, want refunded %d", srv.Balance(), DEFAULT_BALANCE)
+	}
+}
+
+func TestServer_PayInquiry(t *testing.T) {
+	ctx := context.Background()
+	srv := NewServer(WithTransition(StayPending))
+	defer srv.Close()
+
+	cln, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inq, err := cln.Inquiry(ctx, &sat.InquiryRequest{
+		ProductCode:  "pln-postpaid",
+		ClientNumber: "512345678901",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order, err := cln.PayInquiry(ctx, inq, sat.WithPayRequestID("order-1"))
+	if err != nil || order.ProductCode != "pln-postpaid" || order.ClientNumber != "512345678901" {
+		t.Fatalf("PayInquiry() got = %v, %v", order, err)
+	}
+
+	if order.SalesPrice != inq.SalesPrice {
+		t.Errorf("PayInquiry() sales price = %d, want %d", order.SalesPrice, inq.SalesPrice)
+	}
+}
@@ -0,0 +1,195 @@
+package sat
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/tokopedia/golang-sat/internal/pool"
+)
+
+// StreamOptions contains field you can configure on the batch check status and batch inquiry
+type StreamOptions struct {
+	// Concurrency is the max in-flight call, default 4
+	Concurrency int
+	// RateLimit is the max call per second to the SAT host, 0 is unlimited.
+	// The running batches of the same client share one limit, the lowest rate of the running batches
+	RateLimit float64
+
+	limiter *pool.Bucket
+	release func()
+}
+
+// StatusResult contains the check status result of a request id, either Order or Err is set
+type StatusResult struct {
+	RequestID string
+	Order     *OrderDetail
+	Err       error
+}
+
+// InquiryResult contains the inquiry result of a request, either Response or Err is set.
+// Indexes contains the position of every identical request in the batch
+type InquiryResult struct {
+	Indexes  []int
+	Request  *InquiryRequest
+	Response *InquiryResponse
+	Err      error
+}
+
+// CheckStatusBatch checks the status of every request id using the client, see CheckStatusBatch
+func (c *Client) CheckStatusBatch(ctx context.Context, requestIDs []string, opts StreamOptions, callOpts ...CallOptionFunc) <-chan *StatusResult {
+	opts.limiter, opts.release = c.batchLimiter.acquire(opts.RateLimit)
+	return CheckStatusBatch(ctx, c, requestIDs, opts, callOpts...)
+}
+
+// InquiryBatch inquires every request using the client, see InquiryBatch
+func (c *Client) InquiryBatch(ctx context.Context, reqs []*InquiryRequest, opts StreamOptions, callOpts ...CallOptionFunc) <-chan *InquiryResult {
+	opts.limiter, opts.release = c.batchLimiter.acquire(opts.RateLimit)
+	return InquiryBatch(ctx, c, reqs, opts, callOpts...)
+}
+
+// CheckStatusBatch checks the status of every request id, the identical request id is checked once.
+// The results are sent as they complete, and the channel is closed after the last result or when ctx is done.
+// Read the channel until it is closed or cancel ctx. The call options are applied to every CheckStatus
+func CheckStatusBatch(ctx context.Context, api API, requestIDs []string, opts StreamOptions, callOpts ...CallOptionFunc) <-chan *StatusResult {
+	var unique []string
+	seen := map[string]bool{}
+	for _, requestID := range requestIDs {
+		if !seen[requestID] {
+			seen[requestID] = true
+			unique = append(unique, requestID)
+		}
+	}
+
+	results := make(chan *StatusResult)
+	go func() {
+		defer close(results)
+
+		fanOut(ctx, len(unique), opts, func(i int) {
+			result := &StatusResult{RequestID: unique[i]}
+			result.Order, result.Err = api.CheckStatus(ctx, unique[i], callOpts...)
+
+			select {
+			case results <- result:
+			case <-ctx.Done():
+			}
+		})
+	}()
+
+	return results
+}
+
+// InquiryBatch inquires every request, the identical request (product code, client number, amount, downline id and fields)
+// is inquired once. The results are sent as they complete, and the channel is closed after the last result or when ctx is done.
+// Read the channel until it is closed or cancel ctx. The call options are applied to every Inquiry
+func InquiryBatch(ctx context.Context, api API, reqs []*InquiryRequest, opts StreamOptions, callOpts ...CallOptionFunc) <-chan *InquiryResult {
+	var unique []*InquiryResult
+	seen := map[string]*InquiryResult{}
+	for i, req := range reqs {
+		key := inquiryBatchKey(req)
+		if result, ok := seen[key]; ok {
+			result.Indexes = append(result.Indexes, i)
+			continue
+		}
+
+		result := &InquiryResult{Indexes: []int{i}, Request: req}
+		seen[key] = result
+		unique = append(unique, result)
+	}
+
+	results := make(chan *InquiryResult)
+	go func() {
+		defer close(results)
+
+		fanOut(ctx, len(unique), opts, func(i int) {
+			result := unique[i]
+			result.Response, result.Err = api.Inquiry(ctx, result.Request, callOpts...)
+
+			select {
+			case results <- result:
+			case <-ctx.Done():
+			}
+		})
+	}()
+
+	return results
+}
+
+// inquiryBatchKey is the key of the identical inquiry in the batch, the request id is not part of the key
+func inquiryBatchKey(req *InquiryRequest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s|%s|%d|%s", req.ProductCode, req.ClientNumber, req.Amount, req.DownlineID)
+	for _, field := range req.Fields {
+		fmt.Fprintf(&b, "|%s=%s", field.Name, field.Value)
+	}
+
+	return b.String()
+}
+
+// fanOut calls fn for every index using the worker pool and the rate limit, it returns after every call is returned.
+// The index not started when ctx is done is skipped
+func fanOut(ctx context.Context, n int, opts StreamOptions, fn func(i int)) {
+	if opts.release != nil {
+		defer opts.release()
+	}
+
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 4
+	}
+
+	limiter := opts.limiter
+	if limiter == nil {
+		limiter = pool.NewBucket(opts.RateLimit, 1)
+	}
+
+	pool.Run(ctx, pool.Indexes(n), opts.Concurrency, limiter, fn)
+}
+
+// batchLimiter is the limiter shared by the running batches of the client,
+// its rate is the lowest rate of the running batches and it is unlimited when none of them has a rate
+type batchLimiter struct {
+	mu     sync.Mutex
+	bucket *pool.Bucket
+	rates  map[int]float64
+	nextID int
+}
+
+// acquire adds the rate of the starting batch, the returned release removes it when the batch is done
+func (l *batchLimiter) acquire(rate float64) (*pool.Bucket, func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.bucket == nil {
+		l.bucket = pool.NewBucket(0, 1)
+		l.rates = map[int]float64{}
+	}
+
+	id := l.nextID
+	l.nextID++
+	l.rates[id] = rate
+	l.bucket.SetRate(l.rate())
+
+	var once sync.Once
+	return l.bucket, func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+
+			delete(l.rates, id)
+			l.bucket.SetRate(l.rate())
+		})
+	}
+}
+
+// rate returns the lowest positive rate of the running batches, 0 is unlimited
+func (l *batchLimiter) rate() float64 {
+	var lowest float64
+	for _, rate := range l.rates {
+		if rate > 0 && (lowest == 0 || rate < lowest) {
+			lowest = rate
+		}
+	}
+
+	return lowest
+}
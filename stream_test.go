@@ -0,0 +1,196 @@
+package sat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCheckStatusBatch(t *testing.T) {
+	var calls, inflight, maxInflight int32
+	next := &Decorator{
+		CheckStatusFunc: func(ctx context.Context, requestID string) (*OrderDetail, error) {
+			atomic.AddInt32(&calls, 1)
+			n := atomic.AddInt32(&inflight, 1)
+			defer atomic.AddInt32(&inflight, -1)
+			for {
+				max := atomic.LoadInt32(&maxInflight)
+				if n <= max || atomic.CompareAndSwapInt32(&maxInflight, max, n) {
+					break
+				}
+			}
+
+			time.Sleep(5 * time.Millisecond)
+			if requestID == "order-3" {
+				return nil, errors.New("connection reset")
+			}
+
+			return &OrderDetail{RequestID: requestID, Status: OrderStatusSuccess}, nil
+		},
+	}
+
+	ids := []string{"order-1", "order-2", "order-1", "order-3", "order-4", "order-2"}
+	got := map[string]*StatusResult{}
+	for result := range CheckStatusBatch(context.Background(), next, ids, StreamOptions{Concurrency: 2}) {
+		got[result.RequestID] = result
+	}
+
+	if calls != 4 || len(got) != 4 {
+		t.Errorf("CheckStatusBatch() called %d times with %d results, want 4", calls, len(got))
+	}
+
+	if maxInflight > 2 {
+		t.Errorf("CheckStatusBatch() in-flight = %d, want <= 2", maxInflight)
+	}
+
+	if got["order-3"].Err == nil || got["order-4"].Order.Status != OrderStatusSuccess {
+		t.Errorf("CheckStatusBatch() got = %v, %v", got["order-3"], got["order-4"])
+	}
+}
+
+func TestInquiryBatch(t *testing.T) {
+	var mu sync.Mutex
+	calls := map[string]int{}
+	next := &Decorator{
+		InquiryFunc: func(ctx context.Context, req *InquiryRequest) (*InquiryResponse, error) {
+			mu.Lock()
+			calls[req.ClientNumber]++
+			mu.Unlock()
+
+			return &InquiryResponse{ProductCode: req.ProductCode, ClientNumber: req.ClientNumber, SalesPrice: 52500}, nil
+		},
+	}
+
+	reqs := []*InquiryRequest{
+		{ProductCode: "pln-postpaid", ClientNumber: "2121212"},
+		{ProductCode: "pln-postpaid", ClientNumber: "3131313"},
+		{ProductCode: "pln-postpaid", ClientNumber: "2121212"},
+		{ProductCode: "pln-postpaid", ClientNumber: "2121212", Fields: []Field{{Name: "month", Value: "2"}}},
+	}
+
+	var results []*InquiryResult
+	for result := range InquiryBatch(context.Background(), next, reqs, StreamOptions{RateLimit: 1000}) {
+		results = append(results, result)
+	}
+
+	if len(results) != 3 || calls["2121212"] != 2 || calls["3131313"] != 1 {
+		t.Fatalf("InquiryBatch() got %d results, calls = %v", len(results), calls)
+	}
+
+	for _, result := range results {
+		if result.Request.ClientNumber == "2121212" && len(result.Request.Fields) == 0 && len(result.Indexes) != 2 {
+			t.Errorf("InquiryBatch() indexes got = %v, want [0 2]", result.Indexes)
+		}
+	}
+}
+
+func TestCheckStatusBatch_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	next := &Decorator{
+		CheckStatusFunc: func(ctx context.Context, requestID string) (*OrderDetail, error) {
+			return &OrderDetail{RequestID: requestID}, nil
+		},
+	}
+
+	results := CheckStatusBatch(ctx, next, []string{"order-1", "order-2", "order-3"}, StreamOptions{})
+	<-results
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		for range results {
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("CheckStatusBatch() channel is not closed after ctx is cancelled")
+	}
+}
+
+func TestBatchLimiter(t *testing.T) {
+	var l batchLimiter
+	limiter, releaseSlow := l.acquire(10)
+	shared, releaseUnlimited := l.acquire(0)
+	if shared != limiter || limiter.Rate() != 10 {
+		t.Errorf("acquire() rate got = %v, want 10 shared by the running batches", limiter.Rate())
+	}
+
+	_, releaseSlower := l.acquire(5)
+	if limiter.Rate() != 5 {
+		t.Errorf("acquire() rate got = %v, want 5", limiter.Rate())
+	}
+
+	releaseSlower()
+	releaseSlower()
+	if limiter.Rate() != 10 {
+		t.Errorf("release() rate got = %v, want 10 restored", limiter.Rate())
+	}
+
+	releaseSlow()
+	releaseUnlimited()
+	if limiter.Rate() != 0 {
+		t.Errorf("release() rate got = %v, want unlimited when no batch is running", limiter.Rate())
+	}
+}
+
+func TestClient_CheckStatusBatchLimit(t *testing.T) {
+	oauthServer := newTestOauthServer()
+	defer oauthServer.Close()
+
+	satServer := newTestCheckStatusServer(&OrderDetail{RequestID: "request_id", Status: OrderStatusSuccess})
+	defer satServer.Close()
+
+	cln, err := NewClient(
+		"abc",
+		"cde",
+		PrivateKeyDummy,
+		WithServerPublicKeyString(PublicKeyDummy),
+		WithHTTPClient(&http.Client{Timeout: 3 * time.Second}),
+		WithAccessTokenURL(oauthServer.URL+"/token"),
+		WithSatBaseURL(satServer.URL),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	for range cln.CheckStatusBatch(ctx, []string{"request_id", "order-1"}, StreamOptions{RateLimit: 5}) {
+	}
+
+	ids := make([]string, 20)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("order-%d", i)
+	}
+
+	// the limit of the finished slow batch doesn't throttle the unlimited batch, 20 calls take 4s at 5 per second.
+	// Only request_id is known by the server, the other orders are answered with 404
+	start := time.Now()
+	for range cln.CheckStatusBatch(ctx, ids, StreamOptions{}) {
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("CheckStatusBatch() unlimited batch took %v, want it not throttled by the finished batch", elapsed)
+	}
+}
+
+func TestCheckStatusBatch_CallOptions(t *testing.T) {
+	next := &Decorator{
+		CheckStatusFunc: func(ctx context.Context, requestID string) (*OrderDetail, error) {
+			return &OrderDetail{RequestID: requestID, ErrorDetail: newCallOption(ctx, nil).correlationID}, nil
+		},
+	}
+
+	for result := range CheckStatusBatch(context.Background(), next, []string{"order-1", "order-2"}, StreamOptions{}, WithCorrelationID("gateway-1")) {
+		if result.Err != nil || result.Order.ErrorDetail != "gateway-1" {
+			t.Errorf("CheckStatusBatch() %s got correlation id = %v, %v, want gateway-1", result.RequestID, result.Order, result.Err)
+		}
+	}
+}
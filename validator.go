@@ -0,0 +1,301 @@
+package sat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProductLookup returns the product by product code, *ProductCatalog satisfies this interface.
+// Either ErrProductNotFound or the SAT 404 error is reported as the unknown product
+type ProductLookup interface {
+	Get(ctx context.Context, code string) (*Product, error)
+}
+
+// FieldError contains the invalid field and the reason
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// Error will return the field and the reason as string
+func (f *FieldError) Error() string {
+	return fmt.Sprintf("%s %s", f.Field, f.Message)
+}
+
+// ValidationError contains every invalid field of the request, returned before the request is sent to SAT
+type ValidationError struct {
+	Errors []*FieldError
+}
+
+// Error will convert all field errors to one string
+func (v *ValidationError) Error() string {
+	messages := make([]string, 0, len(v.Errors))
+	for _, e := range v.Errors {
+		messages = append(messages, e.Error())
+	}
+
+	return fmt.Sprintf("%s - %s", VALIDATION_FAILED, strings.Join(messages, ", "))
+}
+
+// Has will return true when the field is invalid
+func (v *ValidationError) Has(field string) bool {
+	for _, e := range v.Errors {
+		if e.Field == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (v *ValidationError) add(field, message string) {
+	v.Errors = append(v.Errors, &FieldError{Field: field, Message: message})
+}
+
+func (v *ValidationError) err() error {
+	if len(v.Errors) == 0 {
+		return nil
+	}
+
+	return v
+}
+
+// ValidatorOption contains field you can configure on the validator
+type ValidatorOption struct {
+	requestIDMaxLength int
+	requestIDPattern   *regexp.Regexp
+	emptyRequestID     bool
+	inquiryTTL         time.Duration
+}
+
+var defaultValidatorOption = ValidatorOption{
+	requestIDMaxLength: DEFAULT_REQUEST_ID_MAX_LENGTH,
+	requestIDPattern:   defaultRequestIDPattern,
+	inquiryTTL:         DEFAULT_INQUIRY_TTL,
+}
+
+type ValidatorOptionFunc func(*ValidatorOption)
+
+// WithRequestIDMaxLength set the max length of RequestID
+func WithRequestIDMaxLength(requestIDMaxLength int) ValidatorOptionFunc {
+	return func(o *ValidatorOption) {
+		o.requestIDMaxLength = requestIDMaxLength
+	}
+}
+
+// WithRequestIDPattern set the allowed charset of RequestID
+func WithRequestIDPattern(requestIDPattern *regexp.Regexp) ValidatorOptionFunc {
+	return func(o *ValidatorOption) {
+		o.requestIDPattern = requestIDPattern
+	}
+}
+
+// WithEmptyRequestID accept the empty RequestID, when it is filled later by WithRequestIDGenerator of the client
+func WithEmptyRequestID(emptyRequestID bool) ValidatorOptionFunc {
+	return func(o *ValidatorOption) {
+		o.emptyRequestID = emptyRequestID
+	}
+}
+
+// WithInquiryTTL set how long the inquiry result is accepted as the preceding inquiry of checkout
+func WithInquiryTTL(inquiryTTL time.Duration) ValidatorOptionFunc {
+	return func(o *ValidatorOption) {
+		o.inquiryTTL = inquiryTTL
+	}
+}
+
+type inquiryKey struct {
+	productCode  string
+	clientNumber string
+}
+
+type inquiryRecord struct {
+	resp       *InquiryResponse
+	recordedAt time.Time
+}
+
+// Validator checks the checkout and inquiry request against the catalog and the prior inquiry results
+// before the request is sent to SAT. Use Middleware to validate every request of the API
+type Validator struct {
+	products ProductLookup
+	opt      ValidatorOption
+
+	mu        sync.Mutex
+	inquiries map[inquiryKey]inquiryRecord
+}
+
+// NewValidator will return a new validator using the product lookup, example the product catalog
+func NewValidator(products ProductLookup, opts ...ValidatorOptionFunc) *Validator {
+	opt := defaultValidatorOption
+	for _, option := range opts {
+		option(&opt)
+	}
+
+	return &Validator{
+		products:  products,
+		opt:       opt,
+		inquiries: map[inquiryKey]inquiryRecord{},
+	}
+}
+
+// Middleware will return a Middleware validating every inquiry and checkout,
+// the successful inquiry is recorded as the preceding inquiry of the checkout
+func (v *Validator) Middleware() Middleware {
+	return func(next API) API {
+		return &Decorator{
+			Next: next,
+			InquiryFunc: func(ctx context.Context, req *InquiryRequest) (*InquiryResponse, error) {
+				err := v.ValidateInquiry(ctx, req)
+				if err != nil {
+					return nil, err
+				}
+
+				resp, err := next.Inquiry(ctx, req)
+				if err != nil {
+					return nil, err
+				}
+
+				v.RecordInquiry(req, resp)
+				return resp, nil
+			},
+			CheckoutFunc: func(ctx context.Context, req *OrderRequest) (*OrderDetail, error) {
+				err := v.ValidateCheckout(ctx, req)
+				if err != nil {
+					return nil, err
+				}
+
+				return next.Checkout(ctx, req)
+			},
+		}
+	}
+}
+
+// RecordInquiry keeps the inquiry result, it is called by Middleware after every successful inquiry
+func (v *Validator) RecordInquiry(req *InquiryRequest, resp *InquiryResponse) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := time.Now()
+	for key, record := range v.inquiries {
+		if now.Sub(record.recordedAt) > v.opt.inquiryTTL {
+			delete(v.inquiries, key)
+		}
+	}
+
+	v.inquiries[inquiryKey{req.ProductCode, req.ClientNumber}] = inquiryRecord{resp: resp, recordedAt: now}
+}
+
+// ValidateInquiry will return *ValidationError when the inquiry request is invalid
+func (v *Validator) ValidateInquiry(ctx context.Context, req *InquiryRequest) error {
+	verr := &ValidationError{}
+	if req.ClientNumber == "" {
+		verr.add("client_number", "can't be empty")
+	}
+
+	product, err := v.product(ctx, req.ProductCode, verr)
+	if err != nil {
+		return err
+	}
+
+	if product != nil && !product.IsInquiry {
+		verr.add("product_code", "doesn't support inquiry")
+	}
+
+	if req.Amount < 0 {
+		verr.add("amount", "can't be negative")
+	}
+
+	return verr.err()
+}
+
+// ValidateCheckout will return *ValidationError when the checkout request is invalid.
+// The amount is checked against MinPayment, MaxPayment and MinAmount of the preceding inquiry when it is set
+func (v *Validator) ValidateCheckout(ctx context.Context, req *OrderRequest) error {
+	verr := &ValidationError{}
+	if req.RequestID != "" || !v.opt.emptyRequestID {
+		if reason := checkRequestID(req.RequestID, v.opt.requestIDMaxLength, v.opt.requestIDPattern); reason != "" {
+			verr.add("request_id", reason)
+		}
+	}
+
+	if req.ClientNumber == "" {
+		verr.add("client_number", "can't be empty")
+	}
+
+	if req.Amount < 0 {
+		verr.add("amount", "can't be negative")
+	}
+
+	product, err := v.product(ctx, req.ProductCode, verr)
+	if err != nil {
+		return err
+	}
+
+	if product == nil || !product.IsInquiry {
+		return verr.err()
+	}
+
+	inquiry := v.inquiry(req.ProductCode, req.ClientNumber)
+	if inquiry == nil {
+		verr.add("product_code", "requires a preceding inquiry")
+		return verr.err()
+	}
+
+	if req.Amount > 0 {
+		if inquiry.MinPayment > 0 && req.Amount < inquiry.MinPayment {
+			verr.add("amount", fmt.Sprintf("can't be less than min payment %d", inquiry.MinPayment))
+		}
+
+		if inquiry.MaxPayment > 0 && req.Amount > inquiry.MaxPayment {
+			verr.add("amount", fmt.Sprintf("can't be more than max payment %d", inquiry.MaxPayment))
+		}
+
+		if inquiry.MinAmount > 0 && req.Amount < inquiry.MinAmount {
+			verr.add("amount", fmt.Sprintf("can't be less than min amount %d", inquiry.MinAmount))
+		}
+	}
+
+	return verr.err()
+}
+
+// product returns the active product, the invalid product is added to verr.
+// The error is returned only when the product can't be looked up
+func (v *Validator) product(ctx context.Context, code string, verr *ValidationError) (*Product, error) {
+	if code == "" {
+		verr.add("product_code", "can't be empty")
+		return nil, nil
+	}
+
+	product, err := v.products.Get(ctx, code)
+	if errors.Is(err, ErrProductNotFound) || errorStatus(err) == "404" {
+		verr.add("product_code", "is not found")
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if product.Status != ProductStatusActive {
+		verr.add("product_code", "is not active")
+	}
+
+	return product, nil
+}
+
+func (v *Validator) inquiry(productCode, clientNumber string) *InquiryResponse {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	record, ok := v.inquiries[inquiryKey{productCode, clientNumber}]
+	if !ok || time.Since(record.recordedAt) > v.opt.inquiryTTL {
+		return nil
+	}
+
+	return record.resp
+}
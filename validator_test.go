@@ -0,0 +1,116 @@
+package sat
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+)
+
+func TestValidator(t *testing.T) {
+	ctx := context.Background()
+	products := []*Product{
+		{Code: "telkomsel-10k", SalesPrice: 10200, Status: ProductStatusActive},
+		{Code: "indosat-10k", SalesPrice: 10100, Status: ProductTempInactive},
+		{Code: "pln-postpaid", IsInquiry: true, Status: ProductStatusActive},
+	}
+
+	// the miss fallback asks SAT which answers the unknown product with 404
+	lister := &Decorator{
+		ListProductFunc: func(ctx context.Context, code string) ([]*Product, error) {
+			if code == "" {
+				return products, nil
+			}
+
+			return nil, &ErrorResponse{Errors: []*ErrorObject{{Status: "404", Code: "P01", Detail: "product is not found"}}}
+		},
+	}
+
+	catalog := NewProductCatalog(lister, WithCatalogLogger(log.New(io.Discard, "", 0)))
+	err := catalog.Refresh(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkouts := 0
+	next := &Decorator{
+		InquiryFunc: func(ctx context.Context, req *InquiryRequest) (*InquiryResponse, error) {
+			return &InquiryResponse{ProductCode: req.ProductCode, MinPayment: 50000, MaxPayment: 200000}, nil
+		},
+		CheckoutFunc: func(ctx context.Context, req *OrderRequest) (*OrderDetail, error) {
+			checkouts++
+			return &OrderDetail{RequestID: req.RequestID, Status: OrderStatusPending}, nil
+		},
+	}
+	api := Chain(next, NewValidator(catalog).Middleware())
+
+	tests := []struct {
+		name   string
+		req    *OrderRequest
+		fields []string
+	}{
+		{
+			name: "valid",
+			req:  &OrderRequest{RequestID: "order-1", ProductCode: "telkomsel-10k", ClientNumber: "081234567890"},
+		},
+		{
+			name:   "aggregated field errors",
+			req:    &OrderRequest{RequestID: "order 2!", ProductCode: "indosat-10k"},
+			fields: []string{"request_id", "client_number", "product_code"},
+		},
+		{
+			name:   "unknown product",
+			req:    &OrderRequest{RequestID: "order-3", ProductCode: "xl-10k", ClientNumber: "0817"},
+			fields: []string{"product_code"},
+		},
+		{
+			name:   "inquiry product without inquiry",
+			req:    &OrderRequest{RequestID: "order-4", ProductCode: "pln-postpaid", ClientNumber: "2120001"},
+			fields: []string{"product_code"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := api.Checkout(ctx, tt.req)
+			if len(tt.fields) == 0 {
+				if err != nil {
+					t.Errorf("Checkout() error = %v", err)
+				}
+				return
+			}
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) || len(verr.Errors) != len(tt.fields) {
+				t.Fatalf("Checkout() error = %v, want fields %v", err, tt.fields)
+			}
+
+			for _, field := range tt.fields {
+				if !verr.Has(field) {
+					t.Errorf("Checkout() error = %v, want field %s", err, field)
+				}
+			}
+		})
+	}
+
+	_, err = api.Inquiry(ctx, &InquiryRequest{ProductCode: "pln-postpaid", ClientNumber: "2120001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = api.Checkout(ctx, &OrderRequest{RequestID: "order-5", ProductCode: "pln-postpaid", ClientNumber: "2120001", Amount: 300000})
+	var verr *ValidationError
+	if !errors.As(err, &verr) || !verr.Has("amount") {
+		t.Errorf("Checkout() error = %v, want amount above max payment", err)
+	}
+
+	_, err = api.Checkout(ctx, &OrderRequest{RequestID: "order-6", ProductCode: "pln-postpaid", ClientNumber: "2120001", Amount: 100000})
+	if err != nil {
+		t.Errorf("Checkout() error = %v", err)
+	}
+
+	if checkouts != 2 {
+		t.Errorf("Checkout() sent = %d, want only the valid requests", checkouts)
+	}
+}